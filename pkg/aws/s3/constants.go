@@ -4,3 +4,19 @@ const (
 	// S3PrefixSeparator is the S3 prefix separator.
 	S3PrefixSeparator = "/"
 )
+
+// RetentionMode is the Object Lock retention mode applied to an object version.
+type RetentionMode string
+
+const (
+	// RetentionModeGovernance lets users with special permissions shorten or remove the retention.
+	RetentionModeGovernance RetentionMode = "GOVERNANCE"
+
+	// RetentionModeCompliance prevents anyone, including the root user, from shortening or removing the retention.
+	RetentionModeCompliance RetentionMode = "COMPLIANCE"
+)
+
+// IsValid reports whether the retention mode is one supported by S3 Object Lock.
+func (m RetentionMode) IsValid() bool {
+	return m == RetentionModeGovernance || m == RetentionModeCompliance
+}
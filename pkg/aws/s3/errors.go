@@ -0,0 +1,20 @@
+package s3
+
+import "errors"
+
+var (
+	// ErrInvalidRetentionMode indicates an unsupported Object Lock retention mode.
+	ErrInvalidRetentionMode = errors.New("invalid retention mode")
+
+	// ErrRetainUntilRequired indicates a retention mode was set without a retain-until date.
+	ErrRetainUntilRequired = errors.New("retain until date is required with retention mode")
+
+	// ErrRetentionModeRequired indicates a retain-until date was set without a retention mode.
+	ErrRetentionModeRequired = errors.New("retention mode is required with retain until date")
+
+	// ErrRetainUntilInPast indicates the retain-until date is not in the future.
+	ErrRetainUntilInPast = errors.New("retain until date must be in the future")
+
+	// ErrVersionIDEmpty indicates a versionID cannot be empty.
+	ErrVersionIDEmpty = errors.New("versionID cannot be empty")
+)
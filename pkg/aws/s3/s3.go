@@ -4,9 +4,12 @@ package s3
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/hibare/GoCommon/v2/pkg/constants"
 	commonFiles "github.com/hibare/GoCommon/v2/pkg/file"
 )
@@ -24,6 +28,11 @@ type S3APIIface interface {
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
 	ListObjects(ctx context.Context, params *s3.ListObjectsInput, optFns ...func(*s3.Options)) (*s3.ListObjectsOutput, error)
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error)
+	PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error)
 }
 
 // ClientIface is the interface for the S3 service.
@@ -34,8 +43,15 @@ type ClientIface interface {
 
 	UploadDir(ctx context.Context, bucket, prefix, baseDir string, exclude []*regexp.Regexp) (UploadDirResponse, error)
 	UploadFile(ctx context.Context, bucket, prefix, filePath string) (string, error)
+	UploadFileWithOptions(ctx context.Context, bucket, prefix, filePath string, opts UploadOptions) (string, error)
 	ListObjectsAtPrefix(ctx context.Context, bucket, prefix string) ([]string, error)
 	DeleteObjects(ctx context.Context, bucket, key string, recursive bool) error
+
+	ListObjectVersions(ctx context.Context, bucket, prefix string) ([]ObjectVersion, error)
+	GetObjectVersion(ctx context.Context, bucket, key, versionID string) (io.ReadCloser, error)
+	RestoreObjectVersion(ctx context.Context, bucket, key, versionID string) (string, error)
+	SetObjectRetention(ctx context.Context, bucket, key, versionID string, opts RetentionOptions) error
+	SetObjectLegalHold(ctx context.Context, bucket, key, versionID string, enabled bool) error
 }
 
 // client is the implementation of the client service.
@@ -133,6 +149,33 @@ func (s *client) UploadDir(ctx context.Context, bucket, prefix, baseDir string,
 
 // UploadFile uploads a file to the S3 service.
 func (s *client) UploadFile(ctx context.Context, bucket, prefix, filePath string) (string, error) {
+	return s.UploadFileWithOptions(ctx, bucket, prefix, filePath, UploadOptions{})
+}
+
+// UploadOptions holds the Object Lock settings applied to an uploaded object.
+type UploadOptions struct {
+	// RetentionMode is the Object Lock mode; requires RetainUntil.
+	RetentionMode RetentionMode
+	// RetainUntil is the date until which the object version is protected.
+	RetainUntil time.Time
+	// LegalHold places a legal hold on the object version.
+	LegalHold bool
+}
+
+func (o UploadOptions) validate() error {
+	if o.RetentionMode == "" && o.RetainUntil.IsZero() {
+		return nil
+	}
+	return RetentionOptions{Mode: o.RetentionMode, RetainUntil: o.RetainUntil}.validate()
+}
+
+// UploadFileWithOptions uploads a file to the S3 service applying the given Object Lock options.
+// The bucket must have Object Lock enabled when retention or legal hold is requested.
+func (s *client) UploadFileWithOptions(ctx context.Context, bucket, prefix, filePath string, opts UploadOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
 	fp, err := os.Open(filePath)
 	if err != nil {
 		return "", err
@@ -142,11 +185,21 @@ func (s *client) UploadFile(ctx context.Context, bucket, prefix, filePath string
 	}()
 
 	key := filepath.Join(prefix, filepath.Base(filePath))
-	_, err = s.Client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: &bucket,
 		Key:    &key,
 		Body:   fp,
-	})
+	}
+
+	if opts.RetentionMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(opts.RetentionMode)
+		input.ObjectLockRetainUntilDate = aws.Time(opts.RetainUntil)
+	}
+	if opts.LegalHold {
+		input.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+
+	_, err = s.Client.PutObject(ctx, input)
 	if err != nil {
 		return "", err
 	}
@@ -220,6 +273,188 @@ func (s *client) DeleteObjects(ctx context.Context, bucket, key string, recursiv
 	return nil
 }
 
+// ObjectVersion describes a single version (or delete marker) of an object.
+type ObjectVersion struct {
+	Key            string
+	VersionID      string
+	IsLatest       bool
+	IsDeleteMarker bool
+	LastModified   time.Time
+	Size           int64
+	ETag           string
+}
+
+// ListObjectVersions lists all versions and delete markers of the objects under the prefix.
+// Versions are ordered by key and then newest first.
+func (s *client) ListObjectVersions(ctx context.Context, bucket, prefix string) ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	input := &s3.ListObjectVersionsInput{
+		Bucket: &bucket,
+		Prefix: &prefix,
+	}
+
+	for {
+		resp, err := s.Client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range resp.Versions {
+			versions = append(versions, ObjectVersion{
+				Key:          aws.ToString(v.Key),
+				VersionID:    aws.ToString(v.VersionId),
+				IsLatest:     aws.ToBool(v.IsLatest),
+				LastModified: aws.ToTime(v.LastModified),
+				Size:         aws.ToInt64(v.Size),
+				ETag:         aws.ToString(v.ETag),
+			})
+		}
+
+		for _, dm := range resp.DeleteMarkers {
+			versions = append(versions, ObjectVersion{
+				Key:            aws.ToString(dm.Key),
+				VersionID:      aws.ToString(dm.VersionId),
+				IsLatest:       aws.ToBool(dm.IsLatest),
+				IsDeleteMarker: true,
+				LastModified:   aws.ToTime(dm.LastModified),
+			})
+		}
+
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		input.KeyMarker = resp.NextKeyMarker
+		input.VersionIdMarker = resp.NextVersionIdMarker
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Key != versions[j].Key {
+			return versions[i].Key < versions[j].Key
+		}
+		return versions[i].LastModified.After(versions[j].LastModified)
+	})
+
+	return versions, nil
+}
+
+// GetObjectVersion returns the content of a specific object version. The caller must close the reader.
+func (s *client) GetObjectVersion(ctx context.Context, bucket, key, versionID string) (io.ReadCloser, error) {
+	if versionID == "" {
+		return nil, ErrVersionIDEmpty
+	}
+
+	resp, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: &versionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// RestoreObjectVersion restores a previous version by copying it over the current one,
+// which keeps the full version history intact. It returns the version ID of the new latest version.
+func (s *client) RestoreObjectVersion(ctx context.Context, bucket, key, versionID string) (string, error) {
+	if versionID == "" {
+		return "", ErrVersionIDEmpty
+	}
+
+	source := fmt.Sprintf("%s/%s?versionId=%s", bucket, escapeKey(key), url.QueryEscape(versionID))
+	resp, err := s.Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     &bucket,
+		Key:        &key,
+		CopySource: &source,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(resp.VersionId), nil
+}
+
+// RetentionOptions holds the Object Lock retention applied to an existing object version.
+type RetentionOptions struct {
+	Mode        RetentionMode
+	RetainUntil time.Time
+	// BypassGovernance allows shortening or removing a GOVERNANCE retention.
+	BypassGovernance bool
+}
+
+func (o RetentionOptions) validate() error {
+	if o.Mode == "" {
+		return ErrRetentionModeRequired
+	}
+	if !o.Mode.IsValid() {
+		return fmt.Errorf("%w: %s", ErrInvalidRetentionMode, o.Mode)
+	}
+	if o.RetainUntil.IsZero() {
+		return ErrRetainUntilRequired
+	}
+	if !o.RetainUntil.After(time.Now()) {
+		return ErrRetainUntilInPast
+	}
+	return nil
+}
+
+// SetObjectRetention sets the Object Lock retention of an object version.
+// An empty versionID applies the retention to the latest version.
+func (s *client) SetObjectRetention(ctx context.Context, bucket, key, versionID string, opts RetentionOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectRetentionInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Retention: &types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionMode(opts.Mode),
+			RetainUntilDate: aws.Time(opts.RetainUntil),
+		},
+	}
+	if versionID != "" {
+		input.VersionId = &versionID
+	}
+	if opts.BypassGovernance {
+		input.BypassGovernanceRetention = aws.Bool(true)
+	}
+
+	_, err := s.Client.PutObjectRetention(ctx, input)
+	return err
+}
+
+// SetObjectLegalHold turns the legal hold of an object version on or off.
+// An empty versionID applies the legal hold to the latest version.
+func (s *client) SetObjectLegalHold(ctx context.Context, bucket, key, versionID string, enabled bool) error {
+	status := types.ObjectLockLegalHoldStatusOff
+	if enabled {
+		status = types.ObjectLockLegalHoldStatusOn
+	}
+
+	input := &s3.PutObjectLegalHoldInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		LegalHold: &types.ObjectLockLegalHold{Status: status},
+	}
+	if versionID != "" {
+		input.VersionId = &versionID
+	}
+
+	_, err := s.Client.PutObjectLegalHold(ctx, input)
+	return err
+}
+
+// escapeKey URL-encodes each segment of an object key for use in a copy source.
+func escapeKey(key string) string {
+	segments := strings.Split(key, S3PrefixSeparator)
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, S3PrefixSeparator)
+}
+
 // Options is the options for the S3 service.
 type Options struct {
 	Endpoint  string
@@ -3,6 +3,7 @@ package s3
 
 import (
 	"context"
+	"io"
 	"regexp"
 	"testing"
 
@@ -50,6 +51,51 @@ func (m *mockS3API) ListObjects(ctx context.Context, params *s3.ListObjectsInput
 	return args.Get(0).(*s3.ListObjectsOutput), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// ListObjectVersions is a mock implementation of the ListObjectVersions method.
+func (m *mockS3API) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, _ ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.ListObjectVersionsOutput), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// GetObject is a mock implementation of the GetObject method.
+func (m *mockS3API) GetObject(ctx context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.GetObjectOutput), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// CopyObject is a mock implementation of the CopyObject method.
+func (m *mockS3API) CopyObject(ctx context.Context, params *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.CopyObjectOutput), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// PutObjectRetention is a mock implementation of the PutObjectRetention method.
+func (m *mockS3API) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, _ ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.PutObjectRetentionOutput), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// PutObjectLegalHold is a mock implementation of the PutObjectLegalHold method.
+func (m *mockS3API) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, _ ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.PutObjectLegalHoldOutput), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // MockClient is a mock implementation of the Client interface.
 type MockClient struct {
 	mock.Mock
@@ -85,6 +131,12 @@ func (m *MockClient) UploadFile(ctx context.Context, bucket, prefix, filePath st
 	return args.Get(0).(string), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// UploadFileWithOptions is a mock implementation of the UploadFileWithOptions method.
+func (m *MockClient) UploadFileWithOptions(ctx context.Context, bucket, prefix, filePath string, opts UploadOptions) (string, error) {
+	args := m.Called(ctx, bucket, prefix, filePath, opts)
+	return args.Get(0).(string), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // ListObjectsAtPrefix is a mock implementation of the ListObjectsAtPrefix method.
 func (m *MockClient) ListObjectsAtPrefix(ctx context.Context, bucket, prefix string) ([]string, error) {
 	args := m.Called(ctx, bucket, prefix)
@@ -97,6 +149,39 @@ func (m *MockClient) DeleteObjects(ctx context.Context, bucket, key string, recu
 	return args.Error(0)
 }
 
+// ListObjectVersions is a mock implementation of the ListObjectVersions method.
+func (m *MockClient) ListObjectVersions(ctx context.Context, bucket, prefix string) ([]ObjectVersion, error) {
+	args := m.Called(ctx, bucket, prefix)
+	return args.Get(0).([]ObjectVersion), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// GetObjectVersion is a mock implementation of the GetObjectVersion method.
+func (m *MockClient) GetObjectVersion(ctx context.Context, bucket, key, versionID string) (io.ReadCloser, error) {
+	args := m.Called(ctx, bucket, key, versionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// RestoreObjectVersion is a mock implementation of the RestoreObjectVersion method.
+func (m *MockClient) RestoreObjectVersion(ctx context.Context, bucket, key, versionID string) (string, error) {
+	args := m.Called(ctx, bucket, key, versionID)
+	return args.Get(0).(string), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// SetObjectRetention is a mock implementation of the SetObjectRetention method.
+func (m *MockClient) SetObjectRetention(ctx context.Context, bucket, key, versionID string, opts RetentionOptions) error {
+	args := m.Called(ctx, bucket, key, versionID, opts)
+	return args.Error(0)
+}
+
+// SetObjectLegalHold is a mock implementation of the SetObjectLegalHold method.
+func (m *MockClient) SetObjectLegalHold(ctx context.Context, bucket, key, versionID string, enabled bool) error {
+	args := m.Called(ctx, bucket, key, versionID, enabled)
+	return args.Error(0)
+}
+
 // SetMockClient sets the mock client for the S3 package.
 func SetMockClient(t *testing.T) *MockClient {
 	mockClient := new(MockClient)
@@ -2,9 +2,12 @@ package s3
 
 import (
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -165,4 +168,153 @@ func TestS3(t *testing.T) {
 			require.Contains(t, err.Error(), "delete failed")
 		})
 	})
+
+	t.Run("UploadFileWithOptions", func(t *testing.T) {
+		t.Run("object lock", func(t *testing.T) {
+			mockClient := new(mockS3API)
+			s3Client := &client{Client: mockClient}
+			retainUntil := time.Now().Add(24 * time.Hour)
+			mockClient.On("PutObject", t.Context(), mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+				return input.ObjectLockMode == types.ObjectLockModeCompliance &&
+					input.ObjectLockRetainUntilDate != nil && input.ObjectLockRetainUntilDate.Equal(retainUntil) &&
+					input.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn
+			})).Return(&s3.PutObjectOutput{}, nil)
+			key, err := s3Client.UploadFileWithOptions(t.Context(), "bucket", "prefix", filepath.Join(temp, "file1"), UploadOptions{
+				RetentionMode: RetentionModeCompliance,
+				RetainUntil:   retainUntil,
+				LegalHold:     true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, "prefix/file1", key)
+		})
+
+		t.Run("invalid options", func(t *testing.T) {
+			s3Client := &client{Client: new(mockS3API)}
+			tests := []struct {
+				name string
+				opts UploadOptions
+				err  error
+			}{
+				{"mode without date", UploadOptions{RetentionMode: RetentionModeGovernance}, ErrRetainUntilRequired},
+				{"date without mode", UploadOptions{RetainUntil: time.Now().Add(time.Hour)}, ErrRetentionModeRequired},
+				{"invalid mode", UploadOptions{RetentionMode: "FOREVER", RetainUntil: time.Now().Add(time.Hour)}, ErrInvalidRetentionMode},
+				{"date in past", UploadOptions{RetentionMode: RetentionModeGovernance, RetainUntil: time.Now().Add(-time.Hour)}, ErrRetainUntilInPast},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					_, err := s3Client.UploadFileWithOptions(t.Context(), "bucket", "prefix", filepath.Join(temp, "file1"), tt.opts)
+					require.ErrorIs(t, err, tt.err)
+				})
+			}
+		})
+	})
+
+	t.Run("ListObjectVersions", func(t *testing.T) {
+		t.Run("paginated", func(t *testing.T) {
+			mockClient := new(mockS3API)
+			s3Client := &client{Client: mockClient}
+			now := time.Now()
+			mockClient.On("ListObjectVersions", t.Context(), mock.MatchedBy(func(input *s3.ListObjectVersionsInput) bool {
+				return input.KeyMarker == nil
+			})).Return(&s3.ListObjectVersionsOutput{
+				Versions: []types.ObjectVersion{
+					{Key: utils.ToPtr("prefix/a"), VersionId: utils.ToPtr("v1"), LastModified: utils.ToPtr(now.Add(-time.Hour)), Size: utils.ToPtr(int64(5))},
+				},
+				DeleteMarkers: []types.DeleteMarkerEntry{
+					{Key: utils.ToPtr("prefix/a"), VersionId: utils.ToPtr("dm1"), LastModified: utils.ToPtr(now), IsLatest: utils.ToPtr(true)},
+				},
+				IsTruncated:         utils.ToPtr(true),
+				NextKeyMarker:       utils.ToPtr("prefix/a"),
+				NextVersionIdMarker: utils.ToPtr("v1"),
+			}, nil).Once()
+			mockClient.On("ListObjectVersions", t.Context(), mock.MatchedBy(func(input *s3.ListObjectVersionsInput) bool {
+				return input.KeyMarker != nil && *input.VersionIdMarker == "v1"
+			})).Return(&s3.ListObjectVersionsOutput{
+				Versions: []types.ObjectVersion{
+					{Key: utils.ToPtr("prefix/b"), VersionId: utils.ToPtr("v2"), LastModified: utils.ToPtr(now), IsLatest: utils.ToPtr(true)},
+				},
+			}, nil).Once()
+
+			versions, err := s3Client.ListObjectVersions(t.Context(), "bucket", "prefix")
+			require.NoError(t, err)
+			require.Len(t, versions, 3)
+			require.Equal(t, "dm1", versions[0].VersionID)
+			require.True(t, versions[0].IsDeleteMarker)
+			require.Equal(t, "v1", versions[1].VersionID)
+			require.Equal(t, int64(5), versions[1].Size)
+			require.Equal(t, "prefix/b", versions[2].Key)
+			mockClient.AssertExpectations(t)
+		})
+		t.Run("error", func(t *testing.T) {
+			mockClient := new(mockS3API)
+			s3Client := &client{Client: mockClient}
+			mockClient.On("ListObjectVersions", t.Context(), mock.Anything).Return(nil, errors.New("fail"))
+			versions, err := s3Client.ListObjectVersions(t.Context(), "bucket", "prefix")
+			require.Error(t, err)
+			require.Empty(t, versions)
+		})
+	})
+
+	t.Run("GetObjectVersion", func(t *testing.T) {
+		t.Run("success", func(t *testing.T) {
+			mockClient := new(mockS3API)
+			s3Client := &client{Client: mockClient}
+			mockClient.On("GetObject", t.Context(), mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+				return *input.VersionId == "v1"
+			})).Return(&s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))}, nil)
+			body, err := s3Client.GetObjectVersion(t.Context(), "bucket", "key", "v1")
+			require.NoError(t, err)
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, "data", string(data))
+		})
+		t.Run("empty version", func(t *testing.T) {
+			s3Client := &client{Client: new(mockS3API)}
+			_, err := s3Client.GetObjectVersion(t.Context(), "bucket", "key", "")
+			require.ErrorIs(t, err, ErrVersionIDEmpty)
+		})
+	})
+
+	t.Run("RestoreObjectVersion", func(t *testing.T) {
+		mockClient := new(mockS3API)
+		s3Client := &client{Client: mockClient}
+		mockClient.On("CopyObject", t.Context(), mock.MatchedBy(func(input *s3.CopyObjectInput) bool {
+			return *input.CopySource == "bucket/dir/my%20file?versionId=v1" && *input.Key == "dir/my file"
+		})).Return(&s3.CopyObjectOutput{VersionId: utils.ToPtr("v3")}, nil)
+		versionID, err := s3Client.RestoreObjectVersion(t.Context(), "bucket", "dir/my file", "v1")
+		require.NoError(t, err)
+		require.Equal(t, "v3", versionID)
+	})
+
+	t.Run("SetObjectRetention", func(t *testing.T) {
+		t.Run("success", func(t *testing.T) {
+			mockClient := new(mockS3API)
+			s3Client := &client{Client: mockClient}
+			mockClient.On("PutObjectRetention", t.Context(), mock.MatchedBy(func(input *s3.PutObjectRetentionInput) bool {
+				return input.Retention.Mode == types.ObjectLockRetentionModeGovernance &&
+					*input.VersionId == "v1" && *input.BypassGovernanceRetention
+			})).Return(&s3.PutObjectRetentionOutput{}, nil)
+			err := s3Client.SetObjectRetention(t.Context(), "bucket", "key", "v1", RetentionOptions{
+				Mode:             RetentionModeGovernance,
+				RetainUntil:      time.Now().Add(time.Hour),
+				BypassGovernance: true,
+			})
+			require.NoError(t, err)
+		})
+		t.Run("missing mode", func(t *testing.T) {
+			s3Client := &client{Client: new(mockS3API)}
+			err := s3Client.SetObjectRetention(t.Context(), "bucket", "key", "", RetentionOptions{RetainUntil: time.Now().Add(time.Hour)})
+			require.ErrorIs(t, err, ErrRetentionModeRequired)
+		})
+	})
+
+	t.Run("SetObjectLegalHold", func(t *testing.T) {
+		mockClient := new(mockS3API)
+		s3Client := &client{Client: mockClient}
+		mockClient.On("PutObjectLegalHold", t.Context(), mock.MatchedBy(func(input *s3.PutObjectLegalHoldInput) bool {
+			return input.LegalHold.Status == types.ObjectLockLegalHoldStatusOff && input.VersionId == nil
+		})).Return(&s3.PutObjectLegalHoldOutput{}, nil)
+		err := s3Client.SetObjectLegalHold(t.Context(), "bucket", "key", "", false)
+		require.NoError(t, err)
+	})
 }
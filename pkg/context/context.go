@@ -57,3 +57,11 @@ func (c *Context) GetRequestID() string {
 	}
 	return ""
 }
+
+// RequestIDFromContext returns the request ID stored by Context.WithRequestID in ctx.
+func RequestIDFromContext(ctx context.Context) string {
+	if s, ok := ctx.Value(requestIDKey).(string); ok {
+		return s
+	}
+	return ""
+}
@@ -58,3 +58,15 @@ func TestGetRequestID(t *testing.T) {
 		t.Errorf("Expected request ID '%s', got %s", requestID, ctx.GetRequestID())
 	}
 }
+
+func TestRequestIDFromContext(t *testing.T) {
+	requestID := "12345"
+	ctx := NewContext().WithContext(t.Context()).WithRequestID(requestID).GetContext()
+	if got := RequestIDFromContext(ctx); got != requestID {
+		t.Errorf("Expected request ID '%s', got %s", requestID, got)
+	}
+
+	if got := RequestIDFromContext(context.Background()); got != "" {
+		t.Errorf("Expected empty request ID, got %s", got)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	commonFile "github.com/hibare/GoCommon/v2/pkg/file"
 	commonHTTPClient "github.com/hibare/GoCommon/v2/pkg/http/client"
 )
 
@@ -117,17 +118,7 @@ func (g *GPG) FetchGPGPubKeyFromKeyServer(keyID, keyServerURL string) (*string,
 		return nil, fmt.Errorf("failed to read key data: %w", err)
 	}
 
-	file, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create key file: %w", err)
-	}
-	defer func() {
-		if cErr := file.Close(); cErr != nil && err == nil {
-			err = cErr
-		}
-	}()
-
-	if _, err = file.Write(keyData); err != nil {
+	if err := commonFile.WriteFileAtomic(outputFilePath, keyData, commonFile.AtomicWriteOptions{Perm: 0644}); err != nil {
 		return nil, fmt.Errorf("failed to write key data: %w", err)
 	}
 
@@ -167,7 +158,7 @@ func (g *GPG) EncryptFile(inputFilePath string) (string, error) {
 		_ = plaintext.Close()
 	}()
 
-	output, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	output, err := commonFile.NewAtomicWriter(outputFilePath, commonFile.AtomicWriteOptions{Perm: 0644})
 	if err != nil {
 		return "", fmt.Errorf("failed to create output file: %w", err)
 	}
@@ -179,22 +170,28 @@ func (g *GPG) EncryptFile(inputFilePath string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to create armored output: %w", err)
 	}
-	defer func() {
-		_ = encrypted.Close()
-	}()
 
 	encryptionWriter, err := openpgp.Encrypt(encrypted, entityList, nil, nil, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to initialize encryption: %w", err)
 	}
-	defer func() {
-		_ = encryptionWriter.Close()
-	}()
 
 	if _, err = io.Copy(encryptionWriter, plaintext); err != nil {
 		return "", fmt.Errorf("failed to write encrypted contents: %w", err)
 	}
 
+	// Both writers must be flushed before the output is committed.
+	if err = encryptionWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize encryption: %w", err)
+	}
+	if err = encrypted.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize armored output: %w", err)
+	}
+
+	if err = output.Commit(); err != nil {
+		return "", fmt.Errorf("failed to write output file: %w", err)
+	}
+
 	return outputFilePath, nil
 }
 
@@ -262,7 +259,7 @@ func (g *GPG) DecryptFile(inputFilePath string, passphrase string) (string, erro
 		return "", fmt.Errorf("failed to read PGP message: %w", err)
 	}
 
-	outputFile, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	outputFile, err := commonFile.NewAtomicWriter(outputFilePath, commonFile.AtomicWriteOptions{Perm: 0600})
 	if err != nil {
 		return "", fmt.Errorf("failed to create output file: %w", err)
 	}
@@ -274,6 +271,10 @@ func (g *GPG) DecryptFile(inputFilePath string, passphrase string) (string, erro
 		return "", fmt.Errorf("failed to write decrypted contents: %w", err)
 	}
 
+	if err = outputFile.Commit(); err != nil {
+		return "", fmt.Errorf("failed to write output file: %w", err)
+	}
+
 	return outputFilePath, nil
 }
 
@@ -0,0 +1,472 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hibare/GoCommon/v2/pkg/os/exec"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidDSN is returned when the Postgres DSN cannot be parsed into pg_dump or pg_restore arguments.
+var ErrInvalidDSN = errors.New("invalid dsn")
+
+// BackupOptions configures DB.Backup and DB.Restore.
+type BackupOptions struct {
+	// Exec runs pg_dump and pg_restore; defaults to exec.NewExec().
+	Exec exec.ExecIface
+	// Args are appended to the pg_dump or pg_restore arguments, e.g. --schema=public.
+	Args []string
+	// TempDir stages SQLite backups and restores; defaults to os.TempDir().
+	TempDir string
+}
+
+// BackupRestorer is implemented by the database types supporting DB.Backup and DB.Restore.
+type BackupRestorer interface {
+	// Backup returns a stream of a logical backup of db; closing it releases the backup resources.
+	Backup(ctx context.Context, db *gorm.DB, config DatabaseConfig, opts BackupOptions) (io.ReadCloser, error)
+	// Restore replaces the content of db with the backup read from r.
+	Restore(ctx context.Context, db *gorm.DB, config DatabaseConfig, r io.Reader, opts BackupOptions) error
+}
+
+// Backup returns a stream of a logical backup of the database, which can be fed to archiving,
+// encryption or upload. Read errors report a failed backup; the caller closes the stream.
+func (d *DB) Backup(ctx context.Context, opts BackupOptions) (io.ReadCloser, error) {
+	backupRestorer, ok := d.config.DBType.(BackupRestorer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T does not support backups", ErrUnsupportedDriver, d.config.DBType)
+	}
+	return backupRestorer.Backup(ctx, d.DB, d.config, opts)
+}
+
+// Restore replaces the content of the database with a backup produced by Backup.
+func (d *DB) Restore(ctx context.Context, r io.Reader, opts BackupOptions) error {
+	backupRestorer, ok := d.config.DBType.(BackupRestorer)
+	if !ok {
+		return fmt.Errorf("%w: %T does not support restores", ErrUnsupportedDriver, d.config.DBType)
+	}
+	return backupRestorer.Restore(ctx, d.DB, d.config, r, opts)
+}
+
+// Backup writes a consistent copy of the database with VACUUM INTO to a temporary file and streams it.
+func (s *SQLiteDatabase) Backup(ctx context.Context, db *gorm.DB, _ DatabaseConfig, opts BackupOptions) (io.ReadCloser, error) {
+	dir, err := os.MkdirTemp(opts.TempDir, "sqlite-backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	path := filepath.Join(dir, "backup.db")
+
+	if err := db.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	f, err := os.Open(path) //nolint:gosec // reason: path is created above
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &tempFileReader{File: f, dir: dir}, nil
+}
+
+// Restore stages the backup in a temporary file and copies it over the database with the online backup API,
+// so open connections see the restored content.
+func (s *SQLiteDatabase) Restore(ctx context.Context, db *gorm.DB, _ DatabaseConfig, r io.Reader, opts BackupOptions) error {
+	dir, err := os.MkdirTemp(opts.TempDir, "sqlite-restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	path := filepath.Join(dir, "restore.db")
+	if err := writeFile(path, r); err != nil {
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	dest, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dest.Close()
+	}()
+
+	srcDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = srcDB.Close()
+	}()
+	src, err := srcDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	err = dest.Raw(func(destConn any) error {
+		return src.Raw(func(srcConn any) error {
+			destSQLite, ok := destConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("%w: unexpected connection %T", ErrUnsupportedDriver, destConn)
+			}
+			srcSQLite, ok := srcConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("%w: unexpected connection %T", ErrUnsupportedDriver, srcConn)
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				_ = backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	return nil
+}
+
+// Backup streams a pg_dump archive in custom format, suitable for pg_restore.
+func (p *PostgresDatabase) Backup(ctx context.Context, _ *gorm.DB, config DatabaseConfig, opts BackupOptions) (io.ReadCloser, error) {
+	connArgs, env, err := postgresCommandConn(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	args := append(append([]string{"--format=custom", "--no-password"}, connArgs...), opts.Args...)
+
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := startCommand(ctx, opts.Exec, "pg_dump", args, env, nil, stdoutW)
+	if err != nil {
+		_ = stdoutR.Close()
+		return nil, err
+	}
+
+	return &commandReader{file: stdoutR, done: done}, nil
+}
+
+// Restore feeds a pg_dump archive to pg_restore, dropping and recreating the archived objects
+// in a single transaction.
+func (p *PostgresDatabase) Restore(ctx context.Context, _ *gorm.DB, config DatabaseConfig, r io.Reader, opts BackupOptions) error {
+	connArgs, env, err := postgresCommandConn(config.DSN)
+	if err != nil {
+		return err
+	}
+	args := append(append([]string{"--no-password", "--single-transaction", "--clean", "--if-exists"}, connArgs...), opts.Args...)
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	done, err := startCommand(ctx, opts.Exec, "pg_restore", args, env, stdinR, nil)
+	if err != nil {
+		_ = stdinW.Close()
+		return err
+	}
+
+	_, copyErr := io.Copy(stdinW, r)
+	_ = stdinW.Close()
+
+	if err := <-done; err != nil {
+		return err
+	}
+	if copyErr != nil {
+		return fmt.Errorf("failed to stream backup: %w", copyErr)
+	}
+
+	return nil
+}
+
+// postgresEnvParams are the connection parameters passed to pg_dump and pg_restore through libpq environment variables.
+var postgresEnvParams = []struct{ param, env string }{
+	{"password", "PGPASSWORD"},
+	{"sslmode", "PGSSLMODE"},
+	{"sslrootcert", "PGSSLROOTCERT"},
+	{"sslcert", "PGSSLCERT"},
+	{"sslkey", "PGSSLKEY"},
+	{"connect_timeout", "PGCONNECT_TIMEOUT"},
+	{"application_name", "PGAPPNAME"},
+	{"target_session_attrs", "PGTARGETSESSIONATTRS"},
+}
+
+// postgresCommandConn returns the connection arguments and environment of pg_dump and pg_restore for dsn.
+// Only the host, port, user and database are passed as arguments, so the password does not show in the process list.
+func postgresCommandConn(dsn string) (args, env []string, err error) {
+	var params map[string]string
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		params, err = parsePostgresURL(dsn)
+	} else {
+		params, err = parsePostgresKeywords(dsn)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Like libpq, the database defaults to the user. pg_restore needs it, since it writes a script
+	// to stdout instead of restoring when no database is given.
+	if params["dbname"] == "" {
+		params["dbname"] = params["user"]
+	}
+	if params["dbname"] == "" {
+		return nil, nil, fmt.Errorf("%w: no dbname or user", ErrInvalidDSN)
+	}
+
+	for _, flag := range []struct{ param, name string }{
+		{"host", "--host"},
+		{"port", "--port"},
+		{"user", "--username"},
+		{"dbname", "--dbname"},
+	} {
+		if value := params[flag.param]; value != "" {
+			args = append(args, flag.name+"="+value)
+		}
+	}
+	for _, p := range postgresEnvParams {
+		if value := params[p.param]; value != "" {
+			env = append(env, p.env+"="+value)
+		}
+	}
+
+	return args, env, nil
+}
+
+// parsePostgresURL returns the connection parameters of a postgres:// URL. Errors do not include the URL,
+// which may hold the password.
+func parsePostgresURL(dsn string) (map[string]string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed postgres url", ErrInvalidDSN)
+	}
+
+	params := make(map[string]string)
+	for key, values := range u.Query() {
+		params[key] = values[0]
+	}
+	if u.User != nil {
+		params["user"] = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			params["password"] = password
+		}
+	}
+	params["dbname"] = strings.TrimPrefix(u.Path, "/")
+
+	// libpq accepts comma-separated hosts, each with an optional port.
+	var hosts, ports []string
+	hasPort := false
+	for hostPort := range strings.SplitSeq(u.Host, ",") {
+		host, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			host, port = strings.Trim(hostPort, "[]"), ""
+		}
+		hosts = append(hosts, host)
+		ports = append(ports, port)
+		hasPort = hasPort || port != ""
+	}
+	params["host"] = strings.Join(hosts, ",")
+	if hasPort {
+		params["port"] = strings.Join(ports, ",")
+	}
+
+	return params, nil
+}
+
+// parsePostgresKeywords returns the parameters of a keyword/value connection string, e.g. "host=localhost user=app".
+// Values may be single-quoted, with backslash escapes.
+func parsePostgresKeywords(dsn string) (map[string]string, error) {
+	params := make(map[string]string)
+
+	for s := strings.TrimSpace(dsn); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("%w: expected keyword=value", ErrInvalidDSN)
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t\n\r")
+
+		quoted := strings.HasPrefix(s, "'")
+		if quoted {
+			s = s[1:]
+		}
+
+		var value strings.Builder
+		closed := false
+		i := 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+				continue
+			}
+			if quoted && c == '\'' {
+				closed = true
+				i++
+				break
+			}
+			if !quoted && (c == ' ' || c == '\t' || c == '\n' || c == '\r') {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if quoted && !closed {
+			return nil, fmt.Errorf("%w: unterminated quoted value of %s", ErrInvalidDSN, key)
+		}
+
+		params[key] = value.String()
+		s = s[i:]
+	}
+
+	return params, nil
+}
+
+// startCommand runs name with the extra environment variables env in the background and reports its result
+// on the returned channel. It takes ownership of stdin and stdout, closing them when the command exits so the other pipe ends see EOF or EPIPE.
+func startCommand(ctx context.Context, e exec.ExecIface, name string, args, env []string, stdin, stdout *os.File) (<-chan error, error) {
+	closeStdio := func() {
+		for _, f := range []*os.File{stdin, stdout} {
+			if f != nil {
+				_ = f.Close()
+			}
+		}
+	}
+
+	if e == nil {
+		e = exec.NewExec()
+	}
+
+	path, err := e.LookPath(name)
+	if err != nil {
+		closeStdio()
+		return nil, fmt.Errorf("%s not found: %w", name, err)
+	}
+
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		closeStdio()
+		return nil, err
+	}
+
+	cmd := e.Command(ctx, path, args...).WithStderr(stderrW)
+	if len(env) > 0 {
+		cmd = cmd.WithEnv(env)
+	}
+	if stdin != nil {
+		cmd = cmd.WithStdin(stdin)
+	}
+	if stdout != nil {
+		cmd = cmd.WithStdout(stdout)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		var stderr bytes.Buffer
+		copied := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(&stderr, stderrR)
+			close(copied)
+		}()
+
+		err := cmd.Run()
+		closeStdio()
+		_ = stderrW.Close()
+		<-copied
+		_ = stderrR.Close()
+
+		if err != nil {
+			err = fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		done <- err
+	}()
+
+	return done, nil
+}
+
+// commandReader streams the output of a command, reporting its failure instead of EOF.
+// It does not embed the pipe so io.Copy cannot bypass Read through (*os.File).WriteTo.
+type commandReader struct {
+	file *os.File
+	done <-chan error
+
+	once sync.Once
+	err  error
+}
+
+func (c *commandReader) Read(p []byte) (int, error) {
+	n, err := c.file.Read(p)
+	if errors.Is(err, io.EOF) {
+		if cmdErr := c.wait(); cmdErr != nil {
+			return n, cmdErr
+		}
+	}
+	return n, err
+}
+
+// Close closes the stream and waits for the command, which is stopped by the closed pipe if it is still writing.
+func (c *commandReader) Close() error {
+	err := c.file.Close()
+	_ = c.wait()
+	return err
+}
+
+func (c *commandReader) wait() error {
+	c.once.Do(func() {
+		c.err = <-c.done
+	})
+	return c.err
+}
+
+// tempFileReader streams a temporary file and removes its directory on Close.
+type tempFileReader struct {
+	*os.File
+	dir string
+}
+
+func (t *tempFileReader) Close() error {
+	err := t.File.Close()
+	return errors.Join(err, os.RemoveAll(t.dir))
+}
+
+// writeFile copies r to a new file at path.
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path) //nolint:gosec // reason: path is a temporary file
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -4,6 +4,12 @@ package db
 import (
 	"embed"
 	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 // DatabaseConfig holds the configuration for the database connection.
@@ -12,6 +18,30 @@ type DatabaseConfig struct {
 	MigrationsPath string
 	MigrationsFS   embed.FS
 	DBType         Database
+	// GoMigrations run in version order with the SQL migrations of MigrationsFS, which is optional when they are set.
+	GoMigrations []GoMigration
+
+	// MaxOpenConns limits the open connections of the pool; zero means unlimited.
+	MaxOpenConns int
+	// MaxIdleConns limits the idle connections of the pool; zero keeps the database/sql default.
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections after this duration; zero keeps them forever.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle for this duration; zero keeps them forever.
+	ConnMaxIdleTime time.Duration
+
+	// Logger defaults to NewGormLogger with default options, writing through log/slog.
+	Logger gormLogger.Interface
+	// PrepareStmt caches prepared statements for all queries.
+	PrepareStmt bool
+	// NamingStrategy replaces gorm's default table and column naming.
+	NamingStrategy schema.Namer
+
+	// ReplicaDSNs are read replicas of DSN opened with the same DBType and pool settings.
+	// Reads outside of transactions are spread across the healthy replicas, everything else uses DSN.
+	ReplicaDSNs []string
+	// ReplicaHealthCheckInterval is how often replicas are pinged; defaults to DefaultReplicaHealthCheckInterval.
+	ReplicaHealthCheckInterval time.Duration
 }
 
 // Validate validates the database configuration.
@@ -24,5 +54,54 @@ func (c DatabaseConfig) Validate() error {
 		return errors.New("dbtype is required")
 	}
 
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 || c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+		return errors.New("pool settings must not be negative")
+	}
+
+	if slices.Contains(c.ReplicaDSNs, "") {
+		return errors.New("replica dsn must not be empty")
+	}
+
+	if c.ReplicaHealthCheckInterval < 0 {
+		return errors.New("replica health check interval must not be negative")
+	}
+
+	return nil
+}
+
+// gormConfig returns the gorm options of the configuration.
+func (c DatabaseConfig) gormConfig() *gorm.Config {
+	log := c.Logger
+	if log == nil {
+		log = NewGormLogger(GormLoggerOptions{})
+	}
+
+	return &gorm.Config{
+		Logger:         log,
+		PrepareStmt:    c.PrepareStmt,
+		NamingStrategy: c.NamingStrategy,
+	}
+}
+
+// configurePool applies the pool settings of the configuration to db.
+func (c DatabaseConfig) configurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if c.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+
 	return nil
 }
@@ -25,6 +25,24 @@ func TestDatabaseConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "negative pool setting",
+			config: DatabaseConfig{
+				DSN:          "valid_dsn",
+				DBType:       &MockDatabase{},
+				MaxOpenConns: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty replica DSN",
+			config: DatabaseConfig{
+				DSN:         "valid_dsn",
+				DBType:      &MockDatabase{},
+				ReplicaDSNs: []string{"replica_dsn", ""},
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing DBType",
 			config: DatabaseConfig{
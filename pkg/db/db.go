@@ -3,14 +3,12 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"io/fs"
-	"os"
-	"path/filepath"
-	"sort"
-	"strings"
-	"sync"
 
+	"github.com/golang-migrate/migrate/v4/database"
 	"gorm.io/gorm"
 )
 
@@ -21,156 +19,110 @@ var ErrUnsupportedDriver = errors.New("unsupported database driver")
 type Database interface {
 	Open(config DatabaseConfig) (*gorm.DB, error)
 	Migrate(db *gorm.DB, config DatabaseConfig) error
+	// MigrationDriver returns the golang-migrate driver name and instance for db.
+	// Callers close the driver after use, which must release its connection without closing db.
+	MigrationDriver(db *gorm.DB) (string, database.Driver, error)
 }
 
 // DB wraps a gorm.DB and its configuration.
 type DB struct {
 	DB     *gorm.DB
 	config DatabaseConfig
-}
 
-var (
-	instance *DB
-	mu       sync.Mutex
-)
+	// registry holds the DB under name when it was obtained from a Registry.
+	registry *Registry
+	name     string
+
+	// replicas serves reads when DatabaseConfig.ReplicaDSNs is set.
+	replicas *replicaSet
+}
 
 // Migrate runs the migration for the database.
 func (d *DB) Migrate() error {
 	return d.config.DBType.Migrate(d.DB, d.config)
 }
 
-// Close closes the database connection.
+// Close closes the database connection and removes it from its registry.
 func (d *DB) Close() error {
-	sqlDB, err := d.DB.DB()
-	if err != nil {
-		return err
-	}
-	if err := sqlDB.Close(); err != nil {
-		return err
+	if d.registry != nil {
+		d.registry.remove(d.name, d)
 	}
-	instance = nil
-	return nil
+	return d.close()
 }
 
-// NewClient returns a singleton instance of the database connection.
-func NewClient(ctx context.Context, config DatabaseConfig) (*DB, error) {
-	mu.Lock()
-	defer mu.Unlock()
-
-	if instance != nil {
-		// Check if the existing connection is still alive
-		sqlDB, err := instance.DB.DB()
-		if err == nil && sqlDB.Ping() == nil {
-			// Connection is alive, return the existing instance
-			return instance, nil
-		}
-		// Connection is not alive, close it and create a new one
-		_ = sqlDB.Close()
-		instance = nil
+// close closes the connection pools of the primary and the replicas.
+func (d *DB) close() error {
+	var replicasErr error
+	if d.replicas != nil {
+		replicasErr = d.replicas.close()
 	}
 
-	db, err := config.DBType.Open(config)
+	sqlDB, err := d.DB.DB()
 	if err != nil {
-		return nil, err
-	}
-
-	db = db.WithContext(ctx)
-	instance = &DB{
-		DB:     db,
-		config: config,
+		return errors.Join(err, replicasErr)
 	}
-
-	return instance, nil
+	return errors.Join(sqlDB.Close(), replicasErr)
 }
 
-// RunSQLFromDirectory executes all .sql files found in the specified directory.
-// Files are executed in alphabetical order.
-func (d *DB) RunSQLFromDirectory(dir string) error {
-	var files []string
-
-	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".sql") {
-			files = append(files, path)
-		}
-		return nil
-	})
+// HealthCheck pings the database within ctx and returns the connection pool statistics,
+// e.g. for an HTTP readiness endpoint. The statistics are returned even when the ping fails.
+func (d *DB) HealthCheck(ctx context.Context) (sql.DBStats, error) {
+	sqlDB, err := d.DB.DB()
 	if err != nil {
-		return err
+		return sql.DBStats{}, err
 	}
 
-	// Sort files alphabetically for consistent execution order
-	sort.Strings(files)
-
-	// Execute each SQL file
-	for _, file := range files {
-		if err := d.executeSQLFile(file); err != nil {
-			return err
-		}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return sqlDB.Stats(), fmt.Errorf("database ping failed: %w", err)
 	}
 
-	return nil
+	return sqlDB.Stats(), nil
 }
 
-// RunSQLFromFS executes all .sql files found in the embedded filesystem directory.
-// Files are executed in alphabetical order.
-func (d *DB) RunSQLFromFS(fsys fs.FS, dir string) error {
-	var files []string
-
-	err := fs.WalkDir(fsys, dir, func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".sql") {
-			files = append(files, path)
-		}
-		return nil
-	})
+// New opens an independent database connection. Each call returns a new connection pool,
+// so a process can talk to several databases; the caller closes it.
+// When replicas are configured, reads outside of transactions go to a healthy replica
+// unless the context was derived from ReadFromPrimary.
+func New(ctx context.Context, config DatabaseConfig) (*DB, error) {
+	db, err := config.DBType.Open(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Sort files alphabetically for consistent execution order
-	sort.Strings(files)
+	client := &DB{
+		DB:     db.WithContext(ctx),
+		config: config,
+	}
 
-	// Execute each SQL file
-	for _, file := range files {
-		if err := d.executeSQLFileFS(fsys, file); err != nil {
-			return err
+	if len(config.ReplicaDSNs) > 0 {
+		client.replicas, err = openReplicas(config, db)
+		if err != nil {
+			_ = client.Close()
+			return nil, err
 		}
 	}
 
-	return nil
+	return client, nil
 }
 
-// executeSQLFile reads and executes the SQL content from a filesystem file.
-func (d *DB) executeSQLFile(filePath string) error {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	sql := strings.TrimSpace(string(content))
-	if sql == "" {
-		return nil
-	}
-
-	return d.DB.Exec(sql).Error
+// NewClient returns the connection shared under DefaultClientName in the default registry,
+// opening it on first use or when it is no longer alive.
+func NewClient(ctx context.Context, config DatabaseConfig) (*DB, error) {
+	return defaultRegistry.Get(ctx, DefaultClientName, config)
 }
 
-// executeSQLFileFS reads and executes the SQL content from an embedded FS file.
-func (d *DB) executeSQLFileFS(fsys fs.FS, filePath string) error {
-	content, err := fs.ReadFile(fsys, filePath)
-	if err != nil {
-		return err
-	}
-
-	sql := strings.TrimSpace(string(content))
-	if sql == "" {
-		return nil
-	}
+// RunSQLFromDirectory executes all .sql files found in the specified directory.
+// Files are executed in alphabetical order, on every call and without a transaction;
+// use RunSQLFromDirectoryWithOptions to track applied scripts.
+func (d *DB) RunSQLFromDirectory(dir string) error {
+	_, err := d.RunSQLFromDirectoryWithOptions(dir, ScriptOptions{DisableTracking: true, DisableTransaction: true})
+	return err
+}
 
-	return d.DB.Exec(sql).Error
+// RunSQLFromFS executes all .sql files found in the embedded filesystem directory.
+// Files are executed in alphabetical order, on every call and without a transaction;
+// use RunSQLFromFSWithOptions to track applied scripts.
+func (d *DB) RunSQLFromFS(fsys fs.FS, dir string) error {
+	_, err := d.RunSQLFromFSWithOptions(fsys, dir, ScriptOptions{DisableTracking: true, DisableTransaction: true})
+	return err
 }
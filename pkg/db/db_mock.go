@@ -1,6 +1,7 @@
 package db
 
 import (
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
 )
@@ -28,3 +29,16 @@ func (m *MockDatabase) Migrate(gormDB *gorm.DB, config DatabaseConfig) error {
 	args := m.Called(gormDB, config)
 	return args.Error(0)
 }
+
+// MigrationDriver returns the golang-migrate driver for the database.
+func (m *MockDatabase) MigrationDriver(gormDB *gorm.DB) (string, database.Driver, error) {
+	args := m.Called(gormDB)
+	if args.Get(1) == nil {
+		return args.String(0), nil, args.Error(2)
+	}
+	driver, ok := args.Get(1).(database.Driver)
+	if !ok {
+		return args.String(0), nil, args.Error(2)
+	}
+	return args.String(0), driver, args.Error(2)
+}
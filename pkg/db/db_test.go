@@ -2,12 +2,16 @@ package db
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
@@ -111,3 +115,30 @@ func TestDB_Close(t *testing.T) {
 	err = client.Close()
 	require.NoError(t, err)
 }
+
+func TestDB_HealthCheck(t *testing.T) {
+	config := DatabaseConfig{
+		DSN:             filepath.Join(t.TempDir(), "test.db"),
+		DBType:          &SQLiteDatabase{},
+		MaxOpenConns:    3,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Minute,
+		ConnMaxIdleTime: time.Second,
+		Logger:          gormLogger.Discard,
+		PrepareStmt:     true,
+		NamingStrategy:  schema.NamingStrategy{TablePrefix: "app_"},
+	}
+	client, err := New(t.Context(), config)
+	require.NoError(t, err)
+
+	require.True(t, client.DB.PrepareStmt)
+	require.Equal(t, "app_users", client.DB.NamingStrategy.TableName("User"))
+
+	stats, err := client.HealthCheck(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.MaxOpenConnections)
+
+	require.NoError(t, client.Close())
+	_, err = client.HealthCheck(t.Context())
+	require.Error(t, err)
+}
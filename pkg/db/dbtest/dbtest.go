@@ -0,0 +1,165 @@
+// Package dbtest provides in-memory SQLite databases, rolled-back transactions and fixtures for tests.
+package dbtest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hibare/GoCommon/v2/pkg/db"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// testDBCounter names the in-memory databases so each test gets its own.
+var testDBCounter atomic.Uint64
+
+// NewTestDB returns an isolated in-memory SQLite database migrated with the SQL migrations of migrationsFS,
+// found in the only directory holding *.up.sql files. It is closed when the test ends.
+func NewTestDB(t testing.TB, migrationsFS embed.FS) *db.DB {
+	t.Helper()
+
+	migrationsPath, err := findMigrationsDir(migrationsFS)
+	if err != nil {
+		t.Fatalf("failed to find migrations: %v", err)
+	}
+
+	// A shared cache lets every connection of the pool use the same in-memory database.
+	config := db.DatabaseConfig{
+		DSN:            fmt.Sprintf("file:testdb_%d?mode=memory&cache=shared&_foreign_keys=on", testDBCounter.Add(1)),
+		MigrationsFS:   migrationsFS,
+		MigrationsPath: migrationsPath,
+		DBType:         &db.SQLiteDatabase{},
+	}
+
+	// The test context is canceled before cleanups run, which may still use the database.
+	d, err := db.New(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = d.Close()
+	})
+
+	if migrationsPath != "" {
+		if err := d.Migrate(); err != nil {
+			t.Fatalf("failed to migrate test database: %v", err)
+		}
+	}
+
+	return d
+}
+
+// NewTestTx starts a transaction on d that is rolled back when the test ends, isolating the test's writes.
+// The returned context carries the transaction, so db.Repository and DB.WithTx calls made with it join it.
+func NewTestTx(t testing.TB, d *db.DB) (context.Context, *gorm.DB) {
+	t.Helper()
+
+	tx := d.DB.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin test transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() {
+		_ = tx.Rollback().Error
+	})
+
+	return d.ContextWithTx(t.Context(), tx), tx
+}
+
+// LoadFixtures inserts the rows of fixture files read from fsys using conn, which may be a test transaction.
+// Each file is named after its table with a .yml, .yaml or .json extension and holds a list of rows
+// mapping columns to values. Files are loaded in the given order so foreign keys can be satisfied.
+func LoadFixtures(t testing.TB, conn *gorm.DB, fsys fs.FS, paths ...string) {
+	t.Helper()
+
+	for _, p := range paths {
+		rows, err := readFixture(fsys, p)
+		if err != nil {
+			t.Fatalf("failed to read fixture %s: %v", p, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		table := strings.TrimSuffix(path.Base(p), path.Ext(p))
+		if err := conn.Table(table).Create(&rows).Error; err != nil {
+			t.Fatalf("failed to load fixture %s: %v", p, err)
+		}
+	}
+}
+
+// readFixture decodes the rows of a YAML or JSON fixture file.
+func readFixture(fsys fs.FS, p string) ([]map[string]any, error) {
+	data, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]any
+	switch strings.ToLower(path.Ext(p)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &rows)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&rows)
+		for _, row := range rows {
+			for column, value := range row {
+				row[column] = jsonValue(value)
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported fixture format %q", path.Ext(p))
+	}
+
+	return rows, err
+}
+
+// jsonValue converts JSON numbers to int64 when they are integers, float64 otherwise.
+func jsonValue(value any) any {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	if f, err := number.Float64(); err == nil {
+		return f
+	}
+	return number.String()
+}
+
+// findMigrationsDir returns the directory of fsys holding *.up.sql files, empty when there is none.
+func findMigrationsDir(fsys fs.FS) (string, error) {
+	dirs := make(map[string]struct{})
+	err := fs.WalkDir(fsys, ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(p, ".up.sql") {
+			dirs[path.Dir(p)] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	switch len(dirs) {
+	case 0:
+		return "", nil
+	case 1:
+		for dir := range dirs {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("migrations found in %d directories", len(dirs))
+}
@@ -0,0 +1,109 @@
+package dbtest
+
+import (
+	"embed"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hibare/GoCommon/v2/pkg/db"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+//go:embed test_data/migrations/*.sql
+var testMigrations embed.FS
+
+type testAuthor struct {
+	ID   uint
+	Name string
+}
+
+func (testAuthor) TableName() string {
+	return "authors"
+}
+
+func countRows(t *testing.T, conn *gorm.DB, table string) int64 {
+	t.Helper()
+	var count int64
+	require.NoError(t, conn.Table(table).Count(&count).Error)
+	return count
+}
+
+func TestNewTestDB(t *testing.T) {
+	d := NewTestDB(t, testMigrations)
+	require.True(t, d.DB.Migrator().HasTable("authors"))
+	require.True(t, d.DB.Migrator().HasTable("books"))
+
+	t.Run("databases are isolated", func(t *testing.T) {
+		other := NewTestDB(t, testMigrations)
+		require.NoError(t, other.DB.Exec("INSERT INTO authors (name) VALUES ('other')").Error)
+		require.Zero(t, countRows(t, d.DB, "authors"))
+	})
+
+	t.Run("foreign keys are enforced", func(t *testing.T) {
+		err := d.DB.Exec("INSERT INTO books (author_id, title) VALUES (99, 'orphan')").Error
+		require.Error(t, err)
+	})
+
+	t.Run("without migrations", func(t *testing.T) {
+		d := NewTestDB(t, embed.FS{})
+		require.NoError(t, d.DB.Exec("SELECT 1").Error)
+	})
+}
+
+func TestNewTestTx(t *testing.T) {
+	d := NewTestDB(t, testMigrations)
+	fixtures := os.DirFS("test_data/fixtures")
+
+	t.Run("loads fixtures", func(t *testing.T) {
+		_, tx := NewTestTx(t, d)
+		LoadFixtures(t, tx, fixtures, "authors.yml", "books.json")
+
+		require.Equal(t, int64(2), countRows(t, tx, "authors"))
+
+		var price float64
+		require.NoError(t, tx.Table("books").Where("id = ?", 2).Pluck("price", &price).Error)
+		require.InDelta(t, 8.0, price, 0)
+	})
+
+	t.Run("repository joins the transaction", func(t *testing.T) {
+		ctx, tx := NewTestTx(t, d)
+		repo, err := db.NewRepository[testAuthor](d, db.RepositoryOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Create(ctx, &testAuthor{Name: "Octavia E. Butler"}))
+		require.Equal(t, int64(1), countRows(t, tx, "authors"))
+	})
+
+	t.Run("writes are rolled back", func(t *testing.T) {
+		require.Zero(t, countRows(t, d.DB, "authors"))
+		require.Zero(t, countRows(t, d.DB, "books"))
+	})
+}
+
+func TestReadFixture(t *testing.T) {
+	fsys := fstest.MapFS{
+		"rows.json": {Data: []byte(`[{"id": 1, "big": 9007199254740993, "ratio": 0.5, "name": "a"}]`)},
+		"rows.csv":  {Data: []byte("id\n1\n")},
+	}
+
+	rows, err := readFixture(fsys, "rows.json")
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{"id": int64(1), "big": int64(9007199254740993), "ratio": 0.5, "name": "a"}}, rows)
+
+	_, err = readFixture(fsys, "rows.csv")
+	require.ErrorContains(t, err, "unsupported fixture format")
+}
+
+func TestFindMigrationsDir(t *testing.T) {
+	dir, err := findMigrationsDir(testMigrations)
+	require.NoError(t, err)
+	require.Equal(t, "test_data/migrations", dir)
+
+	_, err = findMigrationsDir(fstest.MapFS{
+		"a/1_x.up.sql": {},
+		"b/1_y.up.sql": {},
+	})
+	require.Error(t, err)
+}
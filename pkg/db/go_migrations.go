@@ -0,0 +1,198 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"gorm.io/gorm"
+)
+
+// goMigrationMarker starts the body golang-migrate passes to the database driver for a Go migration.
+const goMigrationMarker = "-- gocommon:go-migration"
+
+// ErrIrreversibleMigration is returned when rolling back a Go migration without Down.
+var ErrIrreversibleMigration = errors.New("migration is irreversible")
+
+// GoMigration is a migration written in Go, e.g. a data backfill, run in version order with the SQL migrations.
+type GoMigration struct {
+	// Version must not be used by another migration.
+	Version uint
+	Name    string
+	// Up applies the migration within tx.
+	Up func(ctx context.Context, tx *gorm.DB) error
+	// Down rolls the migration back within tx; nil makes it irreversible.
+	Down func(ctx context.Context, tx *gorm.DB) error
+}
+
+// goMigrationSource is a golang-migrate source merging Go migrations into the SQL migrations of sql.
+type goMigrationSource struct {
+	sql        source.Driver
+	migrations map[uint]GoMigration
+	versions   []uint
+}
+
+// newGoMigrationSource returns a source serving sql, which may be nil, and migrations in version order.
+func newGoMigrationSource(sql source.Driver, migrations []GoMigration) (*goMigrationSource, error) {
+	s := &goMigrationSource{sql: sql, migrations: make(map[uint]GoMigration, len(migrations))}
+
+	if sql != nil {
+		version, err := sql.First()
+		for err == nil {
+			s.versions = append(s.versions, version)
+			version, err = sql.Next(version)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not list migrations: %w", err)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Up == nil {
+			return nil, fmt.Errorf("go migration %d has no Up function", m.Version)
+		}
+		if _, ok := s.migrations[m.Version]; ok || slices.Contains(s.versions, m.Version) {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		s.migrations[m.Version] = m
+		s.versions = append(s.versions, m.Version)
+	}
+	slices.Sort(s.versions)
+
+	return s, nil
+}
+
+// Open is not supported, the source is created with newGoMigrationSource.
+func (s *goMigrationSource) Open(string) (source.Driver, error) {
+	return nil, errors.New("go migration source cannot be opened from a url")
+}
+
+func (s *goMigrationSource) Close() error {
+	if s.sql != nil {
+		return s.sql.Close()
+	}
+	return nil
+}
+
+func (s *goMigrationSource) First() (uint, error) {
+	if len(s.versions) == 0 {
+		return 0, os.ErrNotExist
+	}
+	return s.versions[0], nil
+}
+
+func (s *goMigrationSource) Prev(version uint) (uint, error) {
+	i, ok := slices.BinarySearch(s.versions, version)
+	if !ok || i == 0 {
+		return 0, os.ErrNotExist
+	}
+	return s.versions[i-1], nil
+}
+
+func (s *goMigrationSource) Next(version uint) (uint, error) {
+	i, ok := slices.BinarySearch(s.versions, version)
+	if !ok || i+1 >= len(s.versions) {
+		return 0, os.ErrNotExist
+	}
+	return s.versions[i+1], nil
+}
+
+func (s *goMigrationSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	return s.read(version, "up")
+}
+
+func (s *goMigrationSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	return s.read(version, "down")
+}
+
+// read returns the SQL migration, or a marker body the driver resolves to the Go migration.
+func (s *goMigrationSource) read(version uint, direction string) (io.ReadCloser, string, error) {
+	if m, ok := s.migrations[version]; ok {
+		body := fmt.Sprintf("%s %d %s", goMigrationMarker, version, direction)
+		return io.NopCloser(strings.NewReader(body)), m.Name, nil
+	}
+	if s.sql == nil {
+		return nil, "", os.ErrNotExist
+	}
+	if direction == "up" {
+		return s.sql.ReadUp(version)
+	}
+	return s.sql.ReadDown(version)
+}
+
+// migrationDriver wraps a golang-migrate database driver to run Go migrations and,
+// for dialects without multi-statement support, SQL migrations statement by statement.
+type migrationDriver struct {
+	database.Driver
+
+	db         *gorm.DB
+	migrations map[uint]GoMigration
+	split      bool
+}
+
+// Run executes a migration body.
+func (d *migrationDriver) Run(migration io.Reader) error {
+	body, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	if rest, ok := bytes.CutPrefix(body, []byte(goMigrationMarker)); ok {
+		return d.runGoMigration(string(rest))
+	}
+
+	if !d.split {
+		return d.Driver.Run(bytes.NewReader(body))
+	}
+
+	statements, err := SplitSQLStatements(string(body), DialectMySQL)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if err := d.Driver.Run(strings.NewReader(statement)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGoMigration runs the Go migration described by the rest of a marker body in a transaction.
+func (d *migrationDriver) runGoMigration(marker string) error {
+	var (
+		version   uint
+		direction string
+	)
+	if _, err := fmt.Sscan(marker, &version, &direction); err != nil {
+		return fmt.Errorf("invalid go migration marker %q: %w", marker, err)
+	}
+
+	m, ok := d.migrations[version]
+	if !ok {
+		return fmt.Errorf("go migration %d is not registered", version)
+	}
+
+	fn := m.Up
+	if direction == "down" {
+		fn = m.Down
+	}
+	if fn == nil {
+		return fmt.Errorf("%w: go migration %d has no Down function", ErrIrreversibleMigration, version)
+	}
+
+	ctx := d.db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ctx, tx)
+	})
+}
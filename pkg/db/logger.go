@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	commonContext "github.com/hibare/GoCommon/v2/pkg/context"
+	"github.com/hibare/GoCommon/v2/pkg/logger"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// DefaultSlowQueryThreshold is the duration above which queries are logged as slow.
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// GormLoggerOptions configures NewGormLogger.
+type GormLoggerOptions struct {
+	// Logger defaults to slog.Default() at the time of logging.
+	Logger *slog.Logger
+	// Level is one of logger.LogLevels and defaults to logger.DefaultLoggerLevel.
+	// ERROR logs failed queries, WARN and INFO also log slow queries, DEBUG also logs every query.
+	Level string
+	// SlowThreshold defaults to DefaultSlowQueryThreshold; a negative value disables slow query logging.
+	SlowThreshold time.Duration
+	// RedactParams logs queries with placeholders instead of their parameters.
+	// gorm does not apply it to Raw(...).Scan, which it logs through its own recorder.
+	RedactParams bool
+	// IgnoreRecordNotFound does not log gorm.ErrRecordNotFound as a failure.
+	IgnoreRecordNotFound bool
+}
+
+// gormSlogLogger is a gorm logger writing through log/slog.
+type gormSlogLogger struct {
+	opts  GormLoggerOptions
+	level gormLogger.LogLevel
+}
+
+// NewGormLogger returns a gorm logger writing through log/slog, so queries follow the logger package
+// configuration. Records carry the request ID of the query context. It is the default DatabaseConfig.Logger.
+func NewGormLogger(opts GormLoggerOptions) gormLogger.Interface {
+	if opts.SlowThreshold == 0 {
+		opts.SlowThreshold = DefaultSlowQueryThreshold
+	}
+
+	return &gormSlogLogger{opts: opts, level: gormLevel(opts.Level)}
+}
+
+// gormLevel maps a level of logger.LogLevels to a gorm log level.
+func gormLevel(level string) gormLogger.LogLevel {
+	switch strings.ToUpper(level) {
+	case logger.LogLevelError:
+		return gormLogger.Error
+	case logger.LogLevelDebug:
+		return gormLogger.Info
+	case logger.LogLevelWarn, logger.LogLevelInfo:
+		return gormLogger.Warn
+	default:
+		return gormLevel(logger.DefaultLoggerLevel)
+	}
+}
+
+// LogMode returns a copy of the logger using the gorm log level, e.g. for db.Debug().
+func (l *gormSlogLogger) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+// Info logs a gorm message at info level.
+func (l *gormSlogLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormLogger.Info {
+		l.log(ctx, slog.LevelInfo, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn logs a gorm message at warn level.
+func (l *gormSlogLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormLogger.Warn {
+		l.log(ctx, slog.LevelWarn, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error logs a gorm message at error level.
+func (l *gormSlogLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormLogger.Error {
+		l.log(ctx, slog.LevelError, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a failed query as error, a slow query as warning and any other query as debug.
+func (l *gormSlogLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormLogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	slow := l.opts.SlowThreshold > 0 && elapsed > l.opts.SlowThreshold
+
+	var (
+		level slog.Level
+		msg   string
+	)
+	switch {
+	case err != nil && l.level >= gormLogger.Error &&
+		!(l.opts.IgnoreRecordNotFound && errors.Is(err, gorm.ErrRecordNotFound)):
+		level, msg = slog.LevelError, "Database query failed"
+	case slow && l.level >= gormLogger.Warn:
+		level, msg = slog.LevelWarn, "Slow database query"
+	case l.level >= gormLogger.Info:
+		level, msg = slog.LevelDebug, "Database query"
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Duration("duration", elapsed),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	if slow {
+		attrs = append(attrs, slog.Duration("slow_threshold", l.opts.SlowThreshold))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	l.log(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops the query parameters when they are redacted, so gorm logs the placeholders.
+func (l *gormSlogLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.opts.RedactParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// log writes a record with the request ID of ctx, attributed to the caller of gorm.
+func (l *gormSlogLogger) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	log := l.opts.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+	if !log.Enabled(ctx, level) {
+		return
+	}
+
+	if requestID := commonContext.RequestIDFromContext(ctx); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+
+	record := slog.NewRecord(time.Now(), level, msg, utils.CallerFrame().PC)
+	record.AddAttrs(attrs...)
+	_ = log.Handler().Handle(ctx, record)
+}
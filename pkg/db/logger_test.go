@@ -0,0 +1,137 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	commonContext "github.com/hibare/GoCommon/v2/pkg/context"
+	"github.com/hibare/GoCommon/v2/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// newTestGormLogger returns a logger writing JSON records at any level to the returned buffer.
+func newTestGormLogger(opts GormLoggerOptions) (gormLogger.Interface, *bytes.Buffer) {
+	var buf bytes.Buffer
+	opts.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return NewGormLogger(opts), &buf
+}
+
+func readRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	buf.Reset()
+	return records
+}
+
+func TestGormLogger_Trace(t *testing.T) {
+	ctx := commonContext.NewContext().WithContext(t.Context()).WithRequestID("req-1").GetContext()
+	query := func() (string, int64) { return "SELECT 1", 1 }
+
+	t.Run("debug logs every query", func(t *testing.T) {
+		l, buf := newTestGormLogger(GormLoggerOptions{Level: logger.LogLevelDebug})
+		l.Trace(ctx, time.Now(), query, nil)
+
+		records := readRecords(t, buf)
+		require.Len(t, records, 1)
+		require.Equal(t, "DEBUG", records[0]["level"])
+		require.Equal(t, "Database query", records[0]["msg"])
+		require.Equal(t, "SELECT 1", records[0]["sql"])
+		require.InDelta(t, 1, records[0]["rows"], 0)
+		require.Equal(t, "req-1", records[0]["request_id"])
+	})
+
+	t.Run("info logs slow and failed queries", func(t *testing.T) {
+		l, buf := newTestGormLogger(GormLoggerOptions{Level: logger.LogLevelInfo, SlowThreshold: time.Millisecond})
+		l.Trace(ctx, time.Now(), query, nil)
+		require.Empty(t, readRecords(t, buf))
+
+		l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+		l.Trace(ctx, time.Now(), query, errors.New("boom"))
+
+		records := readRecords(t, buf)
+		require.Len(t, records, 2)
+		require.Equal(t, "WARN", records[0]["level"])
+		require.Equal(t, "Slow database query", records[0]["msg"])
+		require.Equal(t, "ERROR", records[1]["level"])
+		require.Equal(t, "boom", records[1]["error"])
+	})
+
+	t.Run("negative threshold disables slow queries", func(t *testing.T) {
+		l, buf := newTestGormLogger(GormLoggerOptions{SlowThreshold: -1})
+		l.Trace(ctx, time.Now().Add(-time.Hour), query, nil)
+		require.Empty(t, readRecords(t, buf))
+	})
+
+	t.Run("error level ignores slow queries", func(t *testing.T) {
+		l, buf := newTestGormLogger(GormLoggerOptions{Level: logger.LogLevelError})
+		l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+		require.Empty(t, readRecords(t, buf))
+	})
+
+	t.Run("record not found", func(t *testing.T) {
+		l, buf := newTestGormLogger(GormLoggerOptions{IgnoreRecordNotFound: true})
+		l.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+		require.Empty(t, readRecords(t, buf))
+
+		l, buf = newTestGormLogger(GormLoggerOptions{})
+		l.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+		require.Len(t, readRecords(t, buf), 1)
+	})
+
+	t.Run("silent mode", func(t *testing.T) {
+		l, buf := newTestGormLogger(GormLoggerOptions{Level: logger.LogLevelDebug})
+		l.LogMode(gormLogger.Silent).Trace(ctx, time.Now(), query, errors.New("boom"))
+		require.Empty(t, readRecords(t, buf))
+	})
+}
+
+func TestGormLogger_Queries(t *testing.T) {
+	for _, redact := range []bool{false, true} {
+		l, buf := newTestGormLogger(GormLoggerOptions{Level: logger.LogLevelDebug, RedactParams: redact})
+		config := DatabaseConfig{DSN: filepath.Join(t.TempDir(), "test.db"), Logger: l}
+		gormDB, err := (&SQLiteDatabase{}).Open(config)
+		require.NoError(t, err)
+		buf.Reset()
+
+		require.NoError(t, gormDB.Exec("SELECT ? AS name", "secret").Error)
+
+		records := readRecords(t, buf)
+		require.Len(t, records, 1)
+		if redact {
+			require.Equal(t, "SELECT ? AS name", records[0]["sql"])
+		} else {
+			require.Equal(t, `SELECT "secret" AS name`, records[0]["sql"])
+		}
+
+		sqlDB, err := gormDB.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+	}
+}
+
+func TestGormLevel(t *testing.T) {
+	require.Equal(t, gormLogger.Error, gormLevel(logger.LogLevelError))
+	require.Equal(t, gormLogger.Warn, gormLevel(logger.LogLevelWarn))
+	require.Equal(t, gormLogger.Warn, gormLevel("info"))
+	require.Equal(t, gormLogger.Info, gormLevel(logger.LogLevelDebug))
+	require.Equal(t, gormLogger.Warn, gormLevel("unknown"))
+}
+
+func TestDatabaseConfig_DefaultLogger(t *testing.T) {
+	require.IsType(t, &gormSlogLogger{}, DatabaseConfig{}.gormConfig().Logger)
+	require.Equal(t, gormLogger.Discard, DatabaseConfig{Logger: gormLogger.Discard}.gormConfig().Logger)
+}
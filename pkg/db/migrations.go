@@ -0,0 +1,218 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/johejo/golang-migrate-extra/source/iofs"
+	"gorm.io/gorm"
+)
+
+// MigrationInfo describes a migration found in DatabaseConfig.MigrationsFS.
+type MigrationInfo struct {
+	Version uint
+	Name    string
+}
+
+// MigrationStatus reports the migration state of a database.
+type MigrationStatus struct {
+	// Version is the current version, 0 when no migration was applied.
+	Version uint
+	// Dirty is set when the migration to Version failed halfway; fix the schema and call ForceMigrationVersion.
+	Dirty   bool
+	Applied []MigrationInfo
+	Pending []MigrationInfo
+}
+
+// newMigrationSource returns the golang-migrate source for the configured SQL and Go migrations.
+func newMigrationSource(config DatabaseConfig) (source.Driver, error) {
+	var sqlSource source.Driver
+	if config.MigrationsPath != "" || len(config.GoMigrations) == 0 {
+		src, err := iofs.New(config.MigrationsFS, config.MigrationsPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not create source: %w", err)
+		}
+		if len(config.GoMigrations) == 0 {
+			return src, nil
+		}
+		sqlSource = src
+	}
+
+	src, err := newGoMigrationSource(sqlSource, config.GoMigrations)
+	if err != nil {
+		if sqlSource != nil {
+			_ = sqlSource.Close()
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// newMigrate returns a golang-migrate instance for db using the driver of dbType, and a function releasing it.
+// The instance must not be closed with Migrate.Close, which would close the shared *sql.DB of some drivers.
+func newMigrate(dbType Database, db *gorm.DB, config DatabaseConfig) (*migrate.Migrate, func(), error) {
+	driverName, driver, err := dbType.MigrationDriver(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	src, err := newMigrationSource(config)
+	if err != nil {
+		_ = driver.Close()
+		return nil, nil, err
+	}
+	release := func() {
+		_ = src.Close()
+		_ = driver.Close()
+	}
+
+	wrapped := &migrationDriver{
+		Driver:     driver,
+		db:         db,
+		migrations: make(map[uint]GoMigration, len(config.GoMigrations)),
+	}
+	for _, m := range config.GoMigrations {
+		wrapped.migrations[m.Version] = m
+	}
+	// MySQL rejects multi-statement strings unless the DSN sets multiStatements=true, so run them one by one.
+	if d, ok := dbType.(dialecter); ok && d.Dialect() == DialectMySQL {
+		wrapped.split = true
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, driverName, wrapped)
+	if err != nil {
+		release()
+		return nil, nil, fmt.Errorf("could not create migrate instance: %w", err)
+	}
+
+	return m, release, nil
+}
+
+// migrateUp applies all pending migrations.
+func migrateUp(dbType Database, db *gorm.DB, config DatabaseConfig) error {
+	m, release, err := newMigrate(dbType, db, config)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not run migrations: %w", err)
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back the last applied migration.
+func (d *DB) MigrateDown() error {
+	m, release, err := newMigrate(d.config.DBType, d.DB, d.config)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := m.Steps(-1); err != nil {
+		return fmt.Errorf("could not roll back migration: %w", err)
+	}
+
+	return nil
+}
+
+// MigrateTo migrates up or down to the given version.
+func (d *DB) MigrateTo(version uint) error {
+	m, release, err := newMigrate(d.config.DBType, d.DB, d.config)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := m.Migrate(version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not migrate to version %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// MigrationVersion returns the current migration version and whether it is dirty.
+// The version is 0 when no migration was applied.
+func (d *DB) MigrationVersion() (uint, bool, error) {
+	_, driver, err := d.config.DBType.MigrationDriver(d.DB)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		_ = driver.Close()
+	}()
+
+	version, dirty, err := driver.Version()
+	if err != nil {
+		return 0, false, fmt.Errorf("could not read migration version: %w", err)
+	}
+	if version == database.NilVersion {
+		return 0, false, nil
+	}
+
+	return uint(version), dirty, nil //nolint:gosec // reason: checked against NilVersion, versions are never negative
+}
+
+// ForceMigrationVersion sets the migration version and clears the dirty flag without running migrations.
+// Use it after manually repairing a failed migration; -1 resets the database to no version.
+func (d *DB) ForceMigrationVersion(version int) error {
+	m, release, err := newMigrate(d.config.DBType, d.DB, d.config)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := m.Force(version); err != nil {
+		return fmt.Errorf("could not force version %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// MigrationStatus lists the applied and pending migrations.
+func (d *DB) MigrationStatus() (MigrationStatus, error) {
+	var status MigrationStatus
+
+	version, dirty, err := d.MigrationVersion()
+	if err != nil {
+		return status, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	src, err := newMigrationSource(d.config)
+	if err != nil {
+		return status, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	current, err := src.First()
+	for err == nil {
+		info := MigrationInfo{Version: current}
+		if r, name, readErr := src.ReadUp(current); readErr == nil {
+			_ = r.Close()
+			info.Name = name
+		}
+
+		if version != 0 && current <= version {
+			status.Applied = append(status.Applied, info)
+		} else {
+			status.Pending = append(status.Pending, info)
+		}
+
+		current, err = src.Next(current)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return status, fmt.Errorf("could not list migrations: %w", err)
+	}
+
+	return status, nil
+}
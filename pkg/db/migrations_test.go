@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+//go:embed test_data/interleaved_migrations/*.sql
+var testInterleavedMigrations embed.FS
+
+// newTestSQLiteDB opens an unmigrated file-backed SQLite DB using the test migrations.
+func newTestSQLiteDB(t *testing.T) *DB {
+	t.Helper()
+
+	config := DatabaseConfig{
+		DSN:            filepath.Join(t.TempDir(), "test.db"),
+		MigrationsFS:   testMigrationsSqlite,
+		MigrationsPath: "test_data/migrations",
+		DBType:         &SQLiteDatabase{},
+	}
+	gormDB, err := config.DBType.Open(config)
+	require.NoError(t, err)
+
+	d := &DB{DB: gormDB, config: config}
+	t.Cleanup(func() {
+		_ = d.Close()
+	})
+	return d
+}
+
+func TestDB_MigrationControl(t *testing.T) {
+	d := newTestSQLiteDB(t)
+
+	t.Run("status before migrating", func(t *testing.T) {
+		status, err := d.MigrationStatus()
+		require.NoError(t, err)
+		require.Zero(t, status.Version)
+		require.False(t, status.Dirty)
+		require.Empty(t, status.Applied)
+		require.Equal(t, []MigrationInfo{{Version: 1, Name: "test"}, {Version: 2, Name: "add_email"}}, status.Pending)
+	})
+
+	t.Run("goto", func(t *testing.T) {
+		require.NoError(t, d.MigrateTo(1))
+		version, dirty, err := d.MigrationVersion()
+		require.NoError(t, err)
+		require.Equal(t, uint(1), version)
+		require.False(t, dirty)
+
+		// already there
+		require.NoError(t, d.MigrateTo(1))
+	})
+
+	t.Run("up and status", func(t *testing.T) {
+		require.NoError(t, d.Migrate())
+		require.True(t, d.DB.Migrator().HasColumn("tests", "email"))
+
+		status, err := d.MigrationStatus()
+		require.NoError(t, err)
+		require.Equal(t, uint(2), status.Version)
+		require.Len(t, status.Applied, 2)
+		require.Empty(t, status.Pending)
+	})
+
+	t.Run("down one step", func(t *testing.T) {
+		require.NoError(t, d.MigrateDown())
+		require.False(t, d.DB.Migrator().HasColumn("tests", "email"))
+
+		version, _, err := d.MigrationVersion()
+		require.NoError(t, err)
+		require.Equal(t, uint(1), version)
+	})
+
+	t.Run("force clears dirty state", func(t *testing.T) {
+		require.NoError(t, d.DB.Exec("UPDATE schema_migrations SET version = 2, dirty = true").Error)
+
+		version, dirty, err := d.MigrationVersion()
+		require.NoError(t, err)
+		require.Equal(t, uint(2), version)
+		require.True(t, dirty)
+		require.ErrorAs(t, d.Migrate(), new(migrate.ErrDirty))
+
+		require.NoError(t, d.ForceMigrationVersion(1))
+		version, dirty, err = d.MigrationVersion()
+		require.NoError(t, err)
+		require.Equal(t, uint(1), version)
+		require.False(t, dirty)
+	})
+
+	t.Run("down to nothing", func(t *testing.T) {
+		require.NoError(t, d.MigrateDown())
+		require.Error(t, d.MigrateDown())
+
+		status, err := d.MigrationStatus()
+		require.NoError(t, err)
+		require.Zero(t, status.Version)
+		require.Len(t, status.Pending, 2)
+	})
+}
+
+// connHoldingDatabase takes a dedicated connection per migration driver, like the Postgres and MySQL drivers.
+type connHoldingDatabase struct {
+	SQLiteDatabase
+}
+
+type connHoldingDriver struct {
+	database.Driver
+	conn *sql.Conn
+}
+
+func (d connHoldingDriver) Close() error {
+	return errors.Join(d.conn.Close(), d.Driver.Close())
+}
+
+func (c *connHoldingDatabase) MigrationDriver(db *gorm.DB) (string, database.Driver, error) {
+	name, driver, err := c.SQLiteDatabase.MigrationDriver(db)
+	if err != nil {
+		return "", nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return "", nil, err
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return "", nil, err
+	}
+	return name, connHoldingDriver{Driver: driver, conn: conn}, nil
+}
+
+func TestDB_MigrationsReleaseConnections(t *testing.T) {
+	d := newTestSQLiteDB(t)
+	d.config.DBType = &connHoldingDatabase{}
+
+	for range 3 {
+		require.NoError(t, d.MigrateTo(2))
+		require.NoError(t, d.MigrateDown())
+		require.NoError(t, d.ForceMigrationVersion(1))
+		_, err := d.MigrationStatus()
+		require.NoError(t, err)
+	}
+
+	sqlDB, err := d.DB.DB()
+	require.NoError(t, err)
+	require.Zero(t, sqlDB.Stats().InUse)
+
+	// Releasing the drivers leaves the shared pool open.
+	require.NoError(t, sqlDB.Ping())
+}
+
+func TestDB_GoMigrations(t *testing.T) {
+	var seeded bool
+	goMigrations := []GoMigration{
+		{
+			Version: 2,
+			Name:    "seed_items",
+			Up: func(_ context.Context, tx *gorm.DB) error {
+				seeded = true
+				return tx.Exec("INSERT INTO items (name) VALUES ('First Item'), ('Second Item')").Error
+			},
+			Down: func(_ context.Context, tx *gorm.DB) error {
+				return tx.Exec("DELETE FROM items").Error
+			},
+		},
+		{
+			Version: 4,
+			Name:    "backfill_slugs",
+			Up: func(_ context.Context, tx *gorm.DB) error {
+				return tx.Exec("UPDATE items SET slug = lower(replace(name, ' ', '-'))").Error
+			},
+		},
+	}
+
+	config := DatabaseConfig{
+		DSN:            filepath.Join(t.TempDir(), "test.db"),
+		MigrationsFS:   testInterleavedMigrations,
+		MigrationsPath: "test_data/interleaved_migrations",
+		GoMigrations:   goMigrations,
+		DBType:         &SQLiteDatabase{},
+	}
+	d, err := New(t.Context(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = d.Close()
+	})
+
+	t.Run("status lists go migrations", func(t *testing.T) {
+		status, err := d.MigrationStatus()
+		require.NoError(t, err)
+		require.Equal(t, []MigrationInfo{
+			{Version: 1, Name: "create_items"},
+			{Version: 2, Name: "seed_items"},
+			{Version: 3, Name: "add_slug"},
+			{Version: 4, Name: "backfill_slugs"},
+		}, status.Pending)
+	})
+
+	t.Run("interleaved up", func(t *testing.T) {
+		require.NoError(t, d.Migrate())
+		require.True(t, seeded)
+
+		var slugs []string
+		require.NoError(t, d.DB.Table("items").Order("id").Pluck("slug", &slugs).Error)
+		require.Equal(t, []string{"first-item", "second-item"}, slugs)
+
+		version, _, err := d.MigrationVersion()
+		require.NoError(t, err)
+		require.Equal(t, uint(4), version)
+	})
+
+	t.Run("irreversible migration", func(t *testing.T) {
+		require.ErrorIs(t, d.MigrateDown(), ErrIrreversibleMigration)
+		require.NoError(t, d.ForceMigrationVersion(3))
+	})
+
+	t.Run("go down migration", func(t *testing.T) {
+		require.NoError(t, d.MigrateTo(1))
+
+		var count int64
+		require.NoError(t, d.DB.Table("items").Count(&count).Error)
+		require.Zero(t, count)
+		require.False(t, d.DB.Migrator().HasColumn("items", "slug"))
+	})
+
+	t.Run("duplicate version", func(t *testing.T) {
+		config := config
+		config.GoMigrations = append(config.GoMigrations, GoMigration{Version: 3, Up: goMigrations[0].Up})
+		_, err := newMigrationSource(config)
+		require.ErrorContains(t, err, "duplicate migration version 3")
+	})
+
+	t.Run("go migrations only", func(t *testing.T) {
+		config := DatabaseConfig{
+			DSN: filepath.Join(t.TempDir(), "go_only.db"),
+			GoMigrations: []GoMigration{{Version: 1, Name: "create", Up: func(_ context.Context, tx *gorm.DB) error {
+				return tx.Exec("CREATE TABLE go_only (id INTEGER PRIMARY KEY)").Error
+			}}},
+			DBType: &SQLiteDatabase{},
+		}
+		d, err := New(t.Context(), config)
+		require.NoError(t, err)
+		defer func() {
+			_ = d.Close()
+		}()
+
+		require.NoError(t, d.Migrate())
+		require.True(t, d.DB.Migrator().HasTable("go_only"))
+	})
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	migrateMySQL "github.com/golang-migrate/migrate/v4/database/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQLDatabase implements the Database interface for MySQL and MariaDB.
+// Migrations and scripts are split into single statements, so the DSN does not need multiStatements=true.
+type MySQLDatabase struct{}
+
+// Open opens a database connection.
+func (m *MySQLDatabase) Open(config DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(mysql.Open(config.DSN), config.gormConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.configurePool(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate migrates the database.
+func (m *MySQLDatabase) Migrate(db *gorm.DB, config DatabaseConfig) error {
+	return migrateUp(m, db, config)
+}
+
+// MigrationDriver returns the golang-migrate driver for the database.
+func (m *MySQLDatabase) MigrationDriver(db *gorm.DB) (string, database.Driver, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The driver holds a dedicated connection for its lock; closing the driver returns it to the pool.
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return "", nil, fmt.Errorf("could not get mysql connection: %w", err)
+	}
+
+	driver, err := migrateMySQL.WithConnection(context.Background(), conn, &migrateMySQL.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return "", nil, fmt.Errorf("could not create mysql driver: %w", err)
+	}
+
+	return "mysql", driver, nil
+}
+
+// Dialect returns the SQL dialect used to split scripts into statements.
+func (m *MySQLDatabase) Dialect() SQLDialect {
+	return DialectMySQL
+}
@@ -0,0 +1,43 @@
+//go:build integration
+
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLDatabase_Integration(t *testing.T) {
+	_, baseConfig, err := SetupMockMySQLDB(t)
+	require.NoError(t, err)
+
+	config := DatabaseConfig{
+		DSN:            baseConfig.DSN,
+		MigrationsFS:   testMigrationsSqlite,
+		MigrationsPath: "test_data/migrations",
+		DBType:         &MySQLDatabase{},
+	}
+
+	client, err := New(t.Context(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	require.NoError(t, client.Migrate())
+	require.True(t, client.DB.Migrator().HasColumn("tests", "email"))
+
+	status, err := client.MigrationStatus()
+	require.NoError(t, err)
+	require.Equal(t, uint(2), status.Version)
+	require.Empty(t, status.Pending)
+
+	require.NoError(t, client.MigrateDown())
+	require.False(t, client.DB.Migrator().HasColumn("tests", "email"))
+
+	require.NoError(t, client.RunSQLFromDirectory("test_data/sql_scripts"))
+	var count int64
+	require.NoError(t, client.DB.Table("tests").Where("name = ?", "test_from_sql").Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
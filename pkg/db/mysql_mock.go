@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/orlangure/gnomock"
+	"github.com/orlangure/gnomock/preset/mysql"
+)
+
+const (
+	// MySQLTestUser is the test user for the mysql database.
+	MySQLTestUser = "test_user"
+
+	// MySQLTestPass is the test password for the mysql database.
+	MySQLTestPass = "test_pass"
+
+	// MySQLTestDB is the test database for the mysql database.
+	MySQLTestDB = "test_db"
+
+	// MySQLVersion is the version of the mysql database.
+	MySQLVersion = "8"
+)
+
+// SetupMockMySQLDB sets up a mock mysql database.
+func SetupMockMySQLDB(t *testing.T) (*gnomock.Container, DatabaseConfig, error) {
+	p := mysql.Preset(
+		mysql.WithUser(MySQLTestUser, MySQLTestPass),
+		mysql.WithDatabase(MySQLTestDB),
+		mysql.WithVersion(MySQLVersion),
+	)
+
+	container, err := gnomock.Start(p)
+	if err != nil {
+		return nil, DatabaseConfig{}, err
+	}
+
+	t.Cleanup(func() {
+		_ = gnomock.Stop(container)
+	})
+
+	return container, DatabaseConfig{
+		DSN: fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&multiStatements=true",
+			MySQLTestUser,
+			MySQLTestPass,
+			container.DefaultAddress(),
+			MySQLTestDB,
+		),
+	}, nil
+}
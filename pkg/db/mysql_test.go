@@ -0,0 +1,23 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLDatabase_Open(t *testing.T) {
+	t.Run("invalid_dsn", func(t *testing.T) {
+		db := &MySQLDatabase{}
+		gormDB, err := db.Open(DatabaseConfig{DSN: "invalid-dsn"})
+		require.Error(t, err)
+		require.Nil(t, gormDB)
+	})
+
+	t.Run("unreachable_server", func(t *testing.T) {
+		db := &MySQLDatabase{}
+		gormDB, err := db.Open(DatabaseConfig{DSN: "user:pass@tcp(127.0.0.1:1)/test_db?timeout=1s"})
+		require.Error(t, err)
+		require.Nil(t, gormDB)
+	})
+}
@@ -1,12 +1,11 @@
 package db
 
 import (
-	"errors"
+	"context"
 	"fmt"
 
-	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	migratePG "github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/johejo/golang-migrate-extra/source/iofs"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -16,34 +15,46 @@ type PostgresDatabase struct{}
 
 // Open opens a database connection.
 func (p *PostgresDatabase) Open(config DatabaseConfig) (*gorm.DB, error) {
-	return gorm.Open(postgres.Open(config.DSN), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(config.DSN), config.gormConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.configurePool(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
 }
 
 // Migrate migrates the database.
 func (p *PostgresDatabase) Migrate(db *gorm.DB, config DatabaseConfig) error {
-	sqlDB, err := db.DB()
-	if err != nil {
-		return err
-	}
+	return migrateUp(p, db, config)
+}
 
-	driver, err := migratePG.WithInstance(sqlDB, &migratePG.Config{})
+// MigrationDriver returns the golang-migrate driver for the database.
+func (p *PostgresDatabase) MigrationDriver(db *gorm.DB) (string, database.Driver, error) {
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("could not create postgres driver: %w", err)
+		return "", nil, err
 	}
 
-	source, err := iofs.New(config.MigrationsFS, config.MigrationsPath)
+	// The driver holds a dedicated connection for its lock; closing the driver returns it to the pool.
+	conn, err := sqlDB.Conn(context.Background())
 	if err != nil {
-		return fmt.Errorf("could not create source: %w", err)
+		return "", nil, fmt.Errorf("could not get postgres connection: %w", err)
 	}
 
-	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	driver, err := migratePG.WithConnection(context.Background(), conn, &migratePG.Config{})
 	if err != nil {
-		return fmt.Errorf("could not create migrate instance: %w", err)
+		_ = conn.Close()
+		return "", nil, fmt.Errorf("could not create postgres driver: %w", err)
 	}
 
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("could not run migrations: %w", err)
-	}
+	return "postgres", driver, nil
+}
 
-	return nil
+// Dialect returns the SQL dialect used to split scripts into statements.
+func (p *PostgresDatabase) Dialect() SQLDialect {
+	return DialectPostgres
 }
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultClientName is the name NewClient registers its connection under.
+const DefaultClientName = "default"
+
+// ErrClientConfigMismatch is returned when a registered client is requested with a different DSN.
+var ErrClientConfigMismatch = errors.New("client already registered with a different dsn")
+
+// defaultRegistry backs NewClient and the package-level registry functions.
+var defaultRegistry = NewRegistry()
+
+// Registry shares named database connections, e.g. a primary Postgres and a SQLite cache, across a process.
+type Registry struct {
+	mu      sync.Mutex
+	clients map[string]*DB
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{clients: make(map[string]*DB)}
+}
+
+// Get returns the connection registered under name, opening and registering it when it is missing
+// or no longer alive. It returns ErrClientConfigMismatch if name is registered with another DSN.
+func (r *Registry) Get(ctx context.Context, name string, config DatabaseConfig) (*DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.clients[name]; ok {
+		if existing.config.DSN != config.DSN {
+			return nil, fmt.Errorf("%w: %s", ErrClientConfigMismatch, name)
+		}
+
+		// Check if the existing connection is still alive
+		sqlDB, err := existing.DB.DB()
+		if err == nil && sqlDB.Ping() == nil {
+			return existing, nil
+		}
+		// Connection is not alive, close it with its replicas and create a new one
+		delete(r.clients, name)
+		_ = existing.close()
+	}
+
+	client, err := New(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	client.registry = r
+	client.name = name
+	r.clients[name] = client
+
+	return client, nil
+}
+
+// Lookup returns the connection registered under name.
+func (r *Registry) Lookup(name string) (*DB, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client, ok := r.clients[name]
+	return client, ok
+}
+
+// CloseAll closes and unregisters every connection.
+func (r *Registry) CloseAll() error {
+	r.mu.Lock()
+	clients := r.clients
+	r.clients = make(map[string]*DB)
+	r.mu.Unlock()
+
+	var errs []error
+	for name, client := range clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// remove unregisters client if it is still the connection registered under name.
+func (r *Registry) remove(name string, client *DB) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.clients[name] == client {
+		delete(r.clients, name)
+	}
+}
+
+// GetClient returns the connection registered under name in the default registry, see Registry.Get.
+func GetClient(ctx context.Context, name string, config DatabaseConfig) (*DB, error) {
+	return defaultRegistry.Get(ctx, name, config)
+}
+
+// LookupClient returns the connection registered under name in the default registry.
+func LookupClient(name string) (*DB, bool) {
+	return defaultRegistry.Lookup(name)
+}
+
+// CloseAllClients closes every connection of the default registry.
+func CloseAllClients() error {
+	return defaultRegistry.CloseAll()
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := New(t.Context(), DatabaseConfig{DSN: filepath.Join(dir, "primary.db"), DBType: &SQLiteDatabase{}})
+	require.NoError(t, err)
+	cache, err := New(t.Context(), DatabaseConfig{DSN: filepath.Join(dir, "cache.db"), DBType: &SQLiteDatabase{}})
+	require.NoError(t, err)
+
+	require.NoError(t, primary.DB.Exec("CREATE TABLE only_primary (id INTEGER)").Error)
+	require.True(t, primary.DB.Migrator().HasTable("only_primary"))
+	require.False(t, cache.DB.Migrator().HasTable("only_primary"))
+
+	require.NoError(t, primary.Close())
+
+	sqlDB, err := cache.DB.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Ping(), "closing one client must not affect another")
+	require.NoError(t, cache.Close())
+}
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	primaryConfig := DatabaseConfig{DSN: filepath.Join(dir, "primary.db"), DBType: &SQLiteDatabase{}}
+	cacheConfig := DatabaseConfig{DSN: filepath.Join(dir, "cache.db"), DBType: &SQLiteDatabase{}}
+
+	registry := NewRegistry()
+	primary, err := registry.Get(t.Context(), "primary", primaryConfig)
+	require.NoError(t, err)
+	cache, err := registry.Get(t.Context(), "cache", cacheConfig)
+	require.NoError(t, err)
+	require.NotSame(t, primary, cache)
+
+	again, err := registry.Get(t.Context(), "primary", primaryConfig)
+	require.NoError(t, err)
+	require.Same(t, primary, again)
+
+	_, err = registry.Get(t.Context(), "primary", cacheConfig)
+	require.ErrorIs(t, err, ErrClientConfigMismatch)
+
+	found, ok := registry.Lookup("cache")
+	require.True(t, ok)
+	require.Same(t, cache, found)
+
+	require.NoError(t, cache.Close())
+	_, ok = registry.Lookup("cache")
+	require.False(t, ok)
+
+	reopened, err := registry.Get(t.Context(), "cache", cacheConfig)
+	require.NoError(t, err)
+	require.NotSame(t, cache, reopened)
+
+	require.NoError(t, registry.CloseAll())
+	_, ok = registry.Lookup("primary")
+	require.False(t, ok)
+}
@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultReplicaHealthCheckInterval is how often replicas are pinged when none is configured.
+const DefaultReplicaHealthCheckInterval = 5 * time.Second
+
+// replicaCallbackName is the name of the gorm callbacks routing reads to replicas.
+const replicaCallbackName = "gocommon:replicas"
+
+var (
+	// lockingClauseRegexp matches the row locking clauses of Postgres and MySQL, with or without SKIP LOCKED or NOWAIT.
+	lockingClauseRegexp = regexp.MustCompile(`\bfor\s+(update|share|no\s+key\s+update|key\s+share)\b|\block\s+in\s+share\s+mode\b`)
+
+	// primaryOnlyFuncRegexp matches functions that write or depend on session state, and SELECT ... INTO.
+	primaryOnlyFuncRegexp = regexp.MustCompile(
+		`\b(nextval|setval|currval|lastval|pg_\w*advisory\w*|pg_notify|get_lock|release_lock|release_all_locks|last_insert_id|found_rows)\s*\(|\binto\b`)
+)
+
+// ReplicaStatus reports the health of a read replica.
+type ReplicaStatus struct {
+	// Index is the position of the replica in DatabaseConfig.ReplicaDSNs.
+	Index   int
+	Healthy bool
+	// Err is the error that ejected the replica, nil while it is healthy.
+	Err error
+}
+
+// readPrimaryContextKey marks a context whose reads must use the primary.
+type readPrimaryContextKey struct{}
+
+// ReadFromPrimary returns a context whose queries are sent to the primary instead of a replica,
+// e.g. to read a record right after writing it without replication lag.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryContextKey{}, true)
+}
+
+// replica is an open read replica and its health.
+type replica struct {
+	index int
+	db    *gorm.DB
+
+	mu      sync.RWMutex
+	healthy bool
+	err     error
+}
+
+// setHealth records the result of a health check or a failed query, logging state changes.
+func (r *replica) setHealth(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case err != nil && r.healthy:
+		slog.Warn("Ejecting unhealthy database replica", "replica", r.index, "error", err)
+	case err == nil && !r.healthy:
+		slog.Info("Database replica is healthy again", "replica", r.index)
+	}
+	r.healthy = err == nil
+	r.err = err
+}
+
+func (r *replica) status() ReplicaStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return ReplicaStatus{Index: r.index, Healthy: r.healthy, Err: r.err}
+}
+
+// replicaSet routes the reads of a primary connection to its healthy replicas.
+type replicaSet struct {
+	replicas []*replica
+	interval time.Duration
+	next     atomic.Uint64
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// openReplicas opens the replicas of config, routes the reads of primary to them and starts their health checks.
+func openReplicas(config DatabaseConfig, primary *gorm.DB) (*replicaSet, error) {
+	s := &replicaSet{
+		interval: config.ReplicaHealthCheckInterval,
+		done:     make(chan struct{}),
+	}
+	if s.interval <= 0 {
+		s.interval = DefaultReplicaHealthCheckInterval
+	}
+
+	for i, dsn := range config.ReplicaDSNs {
+		replicaConfig := config
+		replicaConfig.DSN = dsn
+		replicaConfig.ReplicaDSNs = nil
+
+		db, err := config.DBType.Open(replicaConfig)
+		if err != nil {
+			_ = s.closeReplicas()
+			return nil, fmt.Errorf("failed to open replica %d: %w", i, err)
+		}
+		s.replicas = append(s.replicas, &replica{index: i, db: db, healthy: true})
+	}
+
+	if err := s.register(primary); err != nil {
+		_ = s.closeReplicas()
+		return nil, fmt.Errorf("failed to register replica callbacks: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	go s.watch(ctx)
+
+	return s, nil
+}
+
+// register installs the callbacks routing reads, and ejecting replicas on connection errors.
+// Exec runs the Raw callbacks, which are left alone so it always uses the primary.
+func (s *replicaSet) register(primary *gorm.DB) error {
+	callbacks := primary.Callback()
+	return errors.Join(
+		callbacks.Query().Before("*").Register(replicaCallbackName, s.routeQuery),
+		callbacks.Row().Before("*").Register(replicaCallbackName, s.routeQuery),
+		callbacks.Query().After("*").Register(replicaCallbackName+":eject", s.eject),
+		callbacks.Row().After("*").Register(replicaCallbackName+":eject", s.eject),
+	)
+}
+
+// routeQuery sends a query to a replica unless it locks rows.
+func (s *replicaSet) routeQuery(db *gorm.DB) {
+	if db.Statement.SQL.Len() > 0 {
+		s.routeRaw(db)
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	s.route(db)
+}
+
+// routeRaw sends the raw SQL of Raw queries to a replica when it is a plain SELECT.
+func (s *replicaSet) routeRaw(db *gorm.DB) {
+	if isReadOnlySQL(db.Statement.SQL.String()) {
+		s.route(db)
+	}
+}
+
+// route switches the statement to a healthy replica, unless it runs in a transaction or must read from the primary.
+func (s *replicaSet) route(db *gorm.DB) {
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if ctx := db.Statement.Context; ctx != nil && ctx.Value(readPrimaryContextKey{}) != nil {
+		return
+	}
+	if r := s.pick(); r != nil {
+		db.Statement.ConnPool = r.db.ConnPool
+	}
+}
+
+// pick returns the next healthy replica in round-robin order, nil when all are ejected.
+func (s *replicaSet) pick() *replica {
+	start := s.next.Add(1)
+	for i := range uint64(len(s.replicas)) {
+		r := s.replicas[(start+i)%uint64(len(s.replicas))]
+		r.mu.RLock()
+		healthy := r.healthy
+		r.mu.RUnlock()
+		if healthy {
+			return r
+		}
+	}
+	return nil
+}
+
+// eject marks the replica that served the statement unhealthy when it failed with a connection error.
+func (s *replicaSet) eject(db *gorm.DB) {
+	if db.Error == nil || !isConnectionError(db.Error) {
+		return
+	}
+	for _, r := range s.replicas {
+		if r.db.ConnPool == db.Statement.ConnPool {
+			r.setHealth(db.Error)
+			return
+		}
+	}
+}
+
+// watch pings the replicas every interval until ctx is done.
+func (s *replicaSet) watch(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+// check pings every replica, ejecting the failing ones and restoring the recovered ones.
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		sqlDB, err := r.db.DB()
+		if err == nil {
+			pingCtx, cancel := context.WithTimeout(ctx, s.interval)
+			err = sqlDB.PingContext(pingCtx)
+			cancel()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		r.setHealth(err)
+	}
+}
+
+func (s *replicaSet) statuses() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, len(s.replicas))
+	for i, r := range s.replicas {
+		statuses[i] = r.status()
+	}
+	return statuses
+}
+
+// close stops the health checks and closes the replicas.
+func (s *replicaSet) close() error {
+	s.stop()
+	<-s.done
+	return s.closeReplicas()
+}
+
+func (s *replicaSet) closeReplicas() error {
+	var errs []error
+	for _, r := range s.replicas {
+		sqlDB, err := r.db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to close replica %d: %w", r.index, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReplicaStatus returns the health of the configured read replicas.
+func (d *DB) ReplicaStatus() []ReplicaStatus {
+	if d.replicas == nil {
+		return nil
+	}
+	return d.replicas.statuses()
+}
+
+// isReadOnlySQL reports whether query is a SELECT that neither locks rows nor calls functions that must run
+// on the primary, such as nextval or advisory locks. Mentions inside string literals also keep it on the primary.
+func isReadOnlySQL(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	return strings.HasPrefix(query, "select") &&
+		!lockingClauseRegexp.MatchString(query) &&
+		!primaryOnlyFuncRegexp.MatchString(query)
+}
+
+// isConnectionError reports whether err means the database could not be reached.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
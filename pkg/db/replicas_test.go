@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestReplicatedDB opens a SQLite primary with one replica, each holding a row naming it.
+func newTestReplicatedDB(t *testing.T) *DB {
+	t.Helper()
+
+	config := DatabaseConfig{
+		DSN:                        filepath.Join(t.TempDir(), "primary.db"),
+		ReplicaDSNs:                []string{filepath.Join(t.TempDir(), "replica.db")},
+		ReplicaHealthCheckInterval: time.Hour,
+		DBType:                     &SQLiteDatabase{},
+	}
+
+	for name, dsn := range map[string]string{"primary": config.DSN, "replica": config.ReplicaDSNs[0]} {
+		seedConfig := config
+		seedConfig.DSN = dsn
+		gormDB, err := seedConfig.DBType.Open(seedConfig)
+		require.NoError(t, err)
+		require.NoError(t, gormDB.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)").Error)
+		require.NoError(t, gormDB.Exec("INSERT INTO items (name) VALUES (?)", name).Error)
+		sqlDB, err := gormDB.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+	}
+
+	d, err := New(t.Context(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = d.Close()
+	})
+	return d
+}
+
+func TestDB_Replicas(t *testing.T) {
+	d := newTestReplicatedDB(t)
+	ctx := t.Context()
+
+	names := func(t *testing.T, db *gorm.DB) []string {
+		t.Helper()
+		var names []string
+		require.NoError(t, db.Table("items").Order("id").Pluck("name", &names).Error)
+		return names
+	}
+
+	t.Run("reads use the replica", func(t *testing.T) {
+		require.Equal(t, []string{"replica"}, names(t, d.DB))
+
+		var name string
+		require.NoError(t, d.DB.Raw("SELECT name FROM items").Scan(&name).Error)
+		require.Equal(t, "replica", name)
+	})
+
+	t.Run("writes use the primary", func(t *testing.T) {
+		require.NoError(t, d.DB.Exec("INSERT INTO items (name) VALUES (?)", "written").Error)
+
+		// Exec never uses a replica, even for a SELECT.
+		var execPool gorm.ConnPool
+		require.NoError(t, d.DB.Callback().Raw().After("*").Register("test:pool", func(db *gorm.DB) {
+			execPool = db.Statement.ConnPool
+		}))
+		require.NoError(t, d.DB.Exec("SELECT name FROM items").Error)
+		require.Same(t, d.DB.ConnPool, execPool)
+		require.NoError(t, d.DB.Table("items").Create(map[string]any{"name": "created"}).Error)
+
+		require.Equal(t, []string{"replica"}, names(t, d.DB))
+		require.Equal(t, []string{"primary", "written", "created"}, names(t, d.DB.WithContext(ReadFromPrimary(ctx))))
+	})
+
+	t.Run("transactions use the primary", func(t *testing.T) {
+		require.NoError(t, d.WithTx(ctx, func(_ context.Context, tx *gorm.DB) error {
+			require.Equal(t, []string{"primary", "written", "created"}, names(t, tx))
+			return nil
+		}, TxOptions{}))
+	})
+
+	t.Run("unhealthy replicas are ejected", func(t *testing.T) {
+		require.Equal(t, []ReplicaStatus{{Index: 0, Healthy: true}}, d.ReplicaStatus())
+
+		sqlDB, err := d.replicas.replicas[0].db.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+		d.replicas.check(ctx)
+
+		status := d.ReplicaStatus()
+		require.Len(t, status, 1)
+		require.False(t, status[0].Healthy)
+		require.Error(t, status[0].Err)
+
+		require.Equal(t, []string{"primary", "written", "created"}, names(t, d.DB))
+	})
+}
+
+func TestDB_ReplicasScripts(t *testing.T) {
+	d := newTestReplicatedDB(t)
+	fsys := fstest.MapFS{
+		"seed/01_items.sql": {Data: []byte("INSERT INTO items (name) VALUES ('seeded');")},
+	}
+
+	// The scripts table only exists on the primary, as on a lagging replica.
+	for range 2 {
+		_, err := d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{})
+		require.NoError(t, err)
+	}
+
+	var count int64
+	require.NoError(t, d.DB.WithContext(ReadFromPrimary(t.Context())).Table("items").Where("name = ?", "seeded").Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+func TestRegistry_ReplacesDeadClientWithReplicas(t *testing.T) {
+	d := newTestReplicatedDB(t)
+	registry := NewRegistry()
+	dead, err := registry.Get(t.Context(), "primary", d.config)
+	require.NoError(t, err)
+
+	sqlDB, err := dead.DB.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	reopened, err := registry.Get(t.Context(), "primary", d.config)
+	require.NoError(t, err)
+	require.NotSame(t, dead, reopened)
+	t.Cleanup(func() {
+		_ = reopened.Close()
+	})
+
+	replicaDB, err := dead.replicas.replicas[0].db.DB()
+	require.NoError(t, err)
+	require.ErrorContains(t, replicaDB.Ping(), "database is closed")
+}
+
+func TestDB_ReplicaStatusWithoutReplicas(t *testing.T) {
+	require.Nil(t, newTestSQLiteDB(t).ReplicaStatus())
+}
+
+func TestIsReadOnlySQL(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM items":                   true,
+		"  select 1;":                           true,
+		"SELECT * FROM items FOR UPDATE":        false,
+		"select * from items for share;":        false,
+		"INSERT INTO items (name) VALUES ('x')": false,
+		"UPDATE items SET name = 'x'":           false,
+
+		"SELECT * FROM items FOR UPDATE SKIP LOCKED":                 false,
+		"SELECT * FROM items FOR SHARE NOWAIT LIMIT 1":               false,
+		"SELECT * FROM items FOR NO KEY UPDATE":                      false,
+		"SELECT * FROM items i FOR KEY SHARE OF i":                   false,
+		"SELECT * FROM items LOCK IN SHARE MODE":                     false,
+		"SELECT * FROM items WHERE id = 1 FOR\n  UPDATE":             false,
+		"SELECT nextval('items_id_seq')":                             false,
+		"SELECT setval('items_id_seq', 10)":                          false,
+		"SELECT pg_advisory_lock(42)":                                false,
+		"SELECT pg_try_advisory_xact_lock(42)":                       false,
+		"SELECT GET_LOCK('job', 10)":                                 false,
+		"SELECT LAST_INSERT_ID()":                                    false,
+		"SELECT * INTO archive FROM items":                           false,
+		"SELECT name FROM items WHERE name = 'formatter'":            true,
+		"SELECT count(*) FROM items WHERE updated_at > now() - '1d'": true,
+	}
+
+	for query, want := range tests {
+		t.Run(query, func(t *testing.T) {
+			require.Equal(t, want, isReadOnlySQL(query))
+		})
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	require.True(t, isConnectionError(driver.ErrBadConn))
+	require.True(t, isConnectionError(&net.OpError{Op: "dial", Err: errors.New("refused")}))
+	require.False(t, isConnectionError(errors.New("syntax error")))
+}
@@ -0,0 +1,413 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultPageSize is the page size of List and ListCursor when none is given.
+	DefaultPageSize = 20
+
+	// MaxPageSize caps the page size requested from List and ListCursor.
+	MaxPageSize = 100
+)
+
+var (
+	// ErrFieldNotAllowed is returned when sorting or filtering on a field that is not whitelisted.
+	ErrFieldNotAllowed = errors.New("field not allowed")
+
+	// ErrInvalidCursor is returned when a cursor token is malformed or was issued for another sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// RepositoryOptions configures NewRepository. Fields are given as column or struct field names.
+type RepositoryOptions struct {
+	// SortableFields may be used as ListOptions.SortBy, in addition to the primary key.
+	// Nullable fields (pointers and sql.Scanner types) are rejected unless tagged not null.
+	SortableFields []string
+	// FilterableFields may be used as ListOptions.Filters keys.
+	FilterableFields []string
+}
+
+// ListOptions selects a page of records.
+type ListOptions struct {
+	// Filters match fields by equality, or by membership for slice values.
+	Filters map[string]any
+	// SortBy defaults to the primary key, which also breaks ties.
+	SortBy string
+	Desc   bool
+	// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+	PageSize int
+	// Page is the 1-based page number used by List.
+	Page int
+	// Cursor is the NextCursor of the previous page used by ListCursor; empty for the first page.
+	Cursor string
+}
+
+// Page is a page of records returned by List.
+type Page[T any] struct {
+	Items    []T
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// CursorPage is a page of records returned by ListCursor.
+type CursorPage[T any] struct {
+	Items []T
+	// NextCursor is an opaque token for the next page, empty on the last page.
+	NextCursor string
+}
+
+// Repository provides CRUD and pagination for the model T on top of a DB.
+// Operations run in the transaction of WithTx when the context carries one.
+type Repository[T any] struct {
+	db         *DB
+	schema     *schema.Schema
+	sortable   map[string]*schema.Field
+	filterable map[string]*schema.Field
+}
+
+// NewRepository returns a Repository for the model T, validating the whitelisted fields against its schema.
+func NewRepository[T any](db *DB, opts RepositoryOptions) (*Repository[T], error) {
+	stmt := &gorm.Statement{DB: db.DB}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("model %s has no primary key", stmt.Schema.Name)
+	}
+
+	r := &Repository[T]{
+		db:         db,
+		schema:     stmt.Schema,
+		sortable:   make(map[string]*schema.Field),
+		filterable: make(map[string]*schema.Field),
+	}
+
+	primary := stmt.Schema.PrioritizedPrimaryField
+	r.sortable[primary.DBName] = primary
+	r.sortable[primary.Name] = primary
+
+	for _, set := range []struct {
+		names  []string
+		fields map[string]*schema.Field
+	}{{opts.SortableFields, r.sortable}, {opts.FilterableFields, r.filterable}} {
+		for _, name := range set.names {
+			field := stmt.Schema.LookUpField(name)
+			if field == nil || field.DBName == "" {
+				return nil, fmt.Errorf("%w: %s is not a column of %s", ErrFieldNotAllowed, name, stmt.Schema.Name)
+			}
+			set.fields[name] = field
+		}
+	}
+
+	// Keyset conditions never match NULL, so ListCursor would skip the records where the sort field is NULL.
+	for _, name := range opts.SortableFields {
+		if isNullable(r.sortable[name]) {
+			return nil, fmt.Errorf("%w: %s is nullable and cannot be sorted by, tag it not null", ErrFieldNotAllowed, name)
+		}
+	}
+
+	return r, nil
+}
+
+// scannerType is the type of sql.Scanner, implemented by nullable types such as sql.NullString.
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+// isNullable reports whether field may hold NULL: pointers and sql.Scanner types, unless it is tagged not null.
+func isNullable(field *schema.Field) bool {
+	if field.NotNull || field.PrimaryKey {
+		return false
+	}
+	return field.FieldType.Kind() == reflect.Pointer || reflect.PointerTo(field.FieldType).Implements(scannerType)
+}
+
+// conn returns the transaction of the repository's DB carried by ctx or the database connection.
+func (r *Repository[T]) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := r.db.TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return r.db.DB.WithContext(ctx)
+}
+
+// Get returns the record with the given primary key, or errors.ErrRecordNotFound.
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	var entity T
+	if err := r.conn(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(&entity).Error; err != nil {
+		return nil, mapNotFound(err)
+	}
+	return &entity, nil
+}
+
+// Create inserts entity, filling its generated fields.
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return r.conn(ctx).Create(entity).Error
+}
+
+// Update saves all fields of an existing entity, or returns errors.ErrRecordNotFound.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	result := r.conn(ctx).Model(entity).Select("*").Omit(clause.Associations).Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// MySQL does not count rows whose values are unchanged, so check the record exists. The check reads
+	// from the primary, where the update ran.
+	id, _ := r.schema.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+	var count int64
+	if err := r.conn(ReadFromPrimary(ctx)).Model(new(T)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return commonErrors.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Upsert inserts entity or updates all its fields when it conflicts on the given columns,
+// which default to the primary key.
+func (r *Repository[T]) Upsert(ctx context.Context, entity *T, conflictColumns ...string) error {
+	onConflict := clause.OnConflict{UpdateAll: true}
+	for _, column := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	return r.conn(ctx).Clauses(onConflict).Create(entity).Error
+}
+
+// Delete removes the record with the given primary key, or returns errors.ErrRecordNotFound.
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	result := r.conn(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return commonErrors.ErrRecordNotFound
+	}
+	return nil
+}
+
+// List returns a page of records using offset pagination, with the total number of matching records.
+func (r *Repository[T]) List(ctx context.Context, opts ListOptions) (Page[T], error) {
+	pageSize := normalizePageSize(opts.PageSize)
+	page := max(opts.Page, 1)
+	result := Page[T]{Page: page, PageSize: pageSize}
+
+	query, err := r.filtered(r.conn(ctx).Model(new(T)), opts.Filters)
+	if err != nil {
+		return result, err
+	}
+	sortField, err := r.sortField(opts.SortBy)
+	if err != nil {
+		return result, err
+	}
+
+	if err := query.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	err = r.ordered(query, sortField, opts.Desc).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result.Items).Error
+
+	return result, err
+}
+
+// cursor is the decoded form of a CursorPage.NextCursor token.
+type cursor struct {
+	SortBy string          `json:"s"`
+	Desc   bool            `json:"d"`
+	Value  json.RawMessage `json:"v"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// ListCursor returns a page of records using keyset pagination, which stays fast and consistent
+// on large tables where rows are inserted between requests.
+func (r *Repository[T]) ListCursor(ctx context.Context, opts ListOptions) (CursorPage[T], error) {
+	var result CursorPage[T]
+	pageSize := normalizePageSize(opts.PageSize)
+
+	query, err := r.filtered(r.conn(ctx).Model(new(T)), opts.Filters)
+	if err != nil {
+		return result, err
+	}
+	sortField, err := r.sortField(opts.SortBy)
+	if err != nil {
+		return result, err
+	}
+	primary := r.schema.PrioritizedPrimaryField
+
+	if opts.Cursor != "" {
+		value, id, err := r.decodeCursor(opts.Cursor, sortField, opts.Desc)
+		if err != nil {
+			return result, err
+		}
+
+		op := ">"
+		if opts.Desc {
+			op = "<"
+		}
+		if sortField == primary {
+			query = query.Where(fmt.Sprintf("? %s ?", op), clause.Column{Table: clause.CurrentTable, Name: primary.DBName}, id)
+		} else {
+			sortColumn := clause.Column{Table: clause.CurrentTable, Name: sortField.DBName}
+			idColumn := clause.Column{Table: clause.CurrentTable, Name: primary.DBName}
+			query = query.Where(
+				fmt.Sprintf("? %s ? OR (? = ? AND ? %s ?)", op, op),
+				sortColumn, value, sortColumn, value, idColumn, id,
+			)
+		}
+	}
+
+	// Fetch one extra record to know whether there is a next page.
+	if err := r.ordered(query, sortField, opts.Desc).Limit(pageSize + 1).Find(&result.Items).Error; err != nil {
+		return result, err
+	}
+	if len(result.Items) <= pageSize {
+		return result, nil
+	}
+
+	result.Items = result.Items[:pageSize]
+	result.NextCursor, err = r.encodeCursor(ctx, &result.Items[pageSize-1], sortField, opts.Desc)
+	return result, err
+}
+
+// filtered applies the whitelisted equality filters.
+func (r *Repository[T]) filtered(query *gorm.DB, filters map[string]any) (*gorm.DB, error) {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	// Keep the generated SQL stable.
+	slices.Sort(names)
+
+	for _, name := range names {
+		field, ok := r.filterable[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot filter on %s", ErrFieldNotAllowed, name)
+		}
+
+		column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		value := filters[name]
+		if kind := reflect.ValueOf(value).Kind(); kind == reflect.Slice || kind == reflect.Array {
+			query = query.Where(clause.IN{Column: column, Values: toValues(value)})
+		} else {
+			query = query.Where(clause.Eq{Column: column, Value: value})
+		}
+	}
+
+	return query, nil
+}
+
+// sortField returns the whitelisted sort field, defaulting to the primary key.
+func (r *Repository[T]) sortField(name string) (*schema.Field, error) {
+	if name == "" {
+		return r.schema.PrioritizedPrimaryField, nil
+	}
+	field, ok := r.sortable[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %s", ErrFieldNotAllowed, name)
+	}
+	return field, nil
+}
+
+// ordered sorts by the given field with the primary key as tie breaker.
+func (r *Repository[T]) ordered(query *gorm.DB, field *schema.Field, desc bool) *gorm.DB {
+	primary := r.schema.PrioritizedPrimaryField
+	columns := []clause.OrderByColumn{{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Desc: desc}}
+	if field != primary {
+		columns = append(columns, clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: primary.DBName}, Desc: desc})
+	}
+	return query.Order(clause.OrderBy{Columns: columns})
+}
+
+func (r *Repository[T]) encodeCursor(ctx context.Context, last *T, sortField *schema.Field, desc bool) (string, error) {
+	item := reflect.ValueOf(last).Elem()
+
+	value, _ := sortField.ValueOf(ctx, item)
+	id, _ := r.schema.PrioritizedPrimaryField.ValueOf(ctx, item)
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	idJSON, err := json.Marshal(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	data, err := json.Marshal(cursor{SortBy: sortField.DBName, Desc: desc, Value: valueJSON, ID: idJSON})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort value and primary key of the last record of the previous page,
+// decoded into the field types so the driver binds them like stored values.
+func (r *Repository[T]) decodeCursor(token string, sortField *schema.Field, desc bool) (any, any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	if c.SortBy != sortField.DBName || c.Desc != desc {
+		return nil, nil, fmt.Errorf("%w: issued for another sort order", ErrInvalidCursor)
+	}
+
+	value := reflect.New(sortField.FieldType)
+	if err := json.Unmarshal(c.Value, value.Interface()); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	id := reflect.New(r.schema.PrioritizedPrimaryField.FieldType)
+	if err := json.Unmarshal(c.ID, id.Interface()); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return value.Elem().Interface(), id.Elem().Interface(), nil
+}
+
+// toValues converts a slice or array to the values of an IN clause.
+func toValues(slice any) []any {
+	v := reflect.ValueOf(slice)
+	values := make([]any, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
+func normalizePageSize(size int) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	return min(size, MaxPageSize)
+}
+
+// mapNotFound maps gorm.ErrRecordNotFound to errors.ErrRecordNotFound.
+func mapNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return commonErrors.ErrRecordNotFound
+	}
+	return err
+}
@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type testArticle struct {
+	ID        uint `gorm:"primaryKey"`
+	Title     string
+	Author    string
+	Views     int
+	Published time.Time
+}
+
+func newTestRepository(t *testing.T) (*DB, *Repository[testArticle]) {
+	t.Helper()
+
+	d := newTestSQLiteDB(t)
+	require.NoError(t, d.DB.AutoMigrate(&testArticle{}))
+
+	repo, err := NewRepository[testArticle](d, RepositoryOptions{
+		SortableFields:   []string{"views", "Published"},
+		FilterableFields: []string{"author"},
+	})
+	require.NoError(t, err)
+	return d, repo
+}
+
+func TestNewRepository(t *testing.T) {
+	d := newTestSQLiteDB(t)
+
+	_, err := NewRepository[testArticle](d, RepositoryOptions{SortableFields: []string{"missing"}})
+	require.ErrorIs(t, err, ErrFieldNotAllowed)
+
+	_, err = NewRepository[testArticle](d, RepositoryOptions{FilterableFields: []string{"Title", "author"}})
+	require.NoError(t, err)
+
+	t.Run("nullable sortable fields", func(t *testing.T) {
+		type nullableArticle struct {
+			ID       uint
+			Archived *time.Time
+			Subtitle sql.NullString
+			Slug     sql.NullString `gorm:"not null"`
+		}
+
+		for _, field := range []string{"archived", "Subtitle"} {
+			_, err := NewRepository[nullableArticle](d, RepositoryOptions{SortableFields: []string{field}})
+			require.ErrorIs(t, err, ErrFieldNotAllowed)
+		}
+
+		_, err := NewRepository[nullableArticle](d, RepositoryOptions{
+			SortableFields:   []string{"slug"},
+			FilterableFields: []string{"archived"},
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	d, repo := newTestRepository(t)
+	ctx := t.Context()
+
+	article := &testArticle{Title: "first", Author: "ann", Views: 1}
+	require.NoError(t, repo.Create(ctx, article))
+	require.NotZero(t, article.ID)
+
+	t.Run("get", func(t *testing.T) {
+		got, err := repo.Get(ctx, article.ID)
+		require.NoError(t, err)
+		require.Equal(t, "first", got.Title)
+
+		_, err = repo.Get(ctx, 999)
+		require.ErrorIs(t, err, commonErrors.ErrRecordNotFound)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		article.Title = "renamed"
+		article.Views = 0
+		require.NoError(t, repo.Update(ctx, article))
+
+		got, err := repo.Get(ctx, article.ID)
+		require.NoError(t, err)
+		require.Equal(t, "renamed", got.Title)
+		require.Zero(t, got.Views)
+
+		require.ErrorIs(t, repo.Update(ctx, &testArticle{ID: 999}), commonErrors.ErrRecordNotFound)
+	})
+
+	t.Run("update without changes", func(t *testing.T) {
+		// Report no affected rows for unchanged values, as MySQL does.
+		callbacks := d.DB.Callback().Update()
+		require.NoError(t, callbacks.After("*").Register("test:unchanged", func(db *gorm.DB) {
+			db.RowsAffected = 0
+		}))
+		t.Cleanup(func() {
+			_ = callbacks.Remove("test:unchanged")
+		})
+
+		require.NoError(t, repo.Update(ctx, article))
+		require.ErrorIs(t, repo.Update(ctx, &testArticle{ID: 999}), commonErrors.ErrRecordNotFound)
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		require.NoError(t, repo.Upsert(ctx, &testArticle{ID: article.ID, Title: "upserted"}))
+		got, err := repo.Get(ctx, article.ID)
+		require.NoError(t, err)
+		require.Equal(t, "upserted", got.Title)
+
+		require.NoError(t, repo.Upsert(ctx, &testArticle{ID: 50, Title: "inserted"}, "id"))
+		_, err = repo.Get(ctx, 50)
+		require.NoError(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, repo.Delete(ctx, 50))
+		require.ErrorIs(t, repo.Delete(ctx, 50), commonErrors.ErrRecordNotFound)
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		err := d.WithTx(ctx, func(ctx context.Context, _ *gorm.DB) error {
+			require.NoError(t, repo.Create(ctx, &testArticle{ID: 60, Title: "rolled back"}))
+			_, err := repo.Get(ctx, 60)
+			require.NoError(t, err)
+			return errors.New("abort")
+		}, TxOptions{})
+		require.Error(t, err)
+
+		_, err = repo.Get(ctx, 60)
+		require.ErrorIs(t, err, commonErrors.ErrRecordNotFound)
+	})
+}
+
+func TestRepository_List(t *testing.T) {
+	_, repo := newTestRepository(t)
+	ctx := t.Context()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 25 {
+		author := "ann"
+		if i%2 == 1 {
+			author = "bob"
+		}
+		require.NoError(t, repo.Create(ctx, &testArticle{
+			Title:     fmt.Sprintf("article %d", i),
+			Author:    author,
+			Views:     i % 5,
+			Published: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	t.Run("offset", func(t *testing.T) {
+		page, err := repo.List(ctx, ListOptions{PageSize: 10, Page: 3})
+		require.NoError(t, err)
+		require.Equal(t, int64(25), page.Total)
+		require.Len(t, page.Items, 5)
+		require.Equal(t, uint(21), page.Items[0].ID)
+
+		page, err = repo.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Equal(t, 1, page.Page)
+		require.Equal(t, DefaultPageSize, page.PageSize)
+		require.Len(t, page.Items, DefaultPageSize)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		page, err := repo.List(ctx, ListOptions{Filters: map[string]any{"author": "bob"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(12), page.Total)
+
+		page, err = repo.List(ctx, ListOptions{Filters: map[string]any{"author": []string{"ann", "bob"}}})
+		require.NoError(t, err)
+		require.Equal(t, int64(25), page.Total)
+	})
+
+	t.Run("not whitelisted", func(t *testing.T) {
+		_, err := repo.List(ctx, ListOptions{Filters: map[string]any{"title": "x"}})
+		require.ErrorIs(t, err, ErrFieldNotAllowed)
+
+		_, err = repo.List(ctx, ListOptions{SortBy: "title"})
+		require.ErrorIs(t, err, ErrFieldNotAllowed)
+	})
+
+	collect := func(t *testing.T, opts ListOptions) []testArticle {
+		t.Helper()
+		var items []testArticle
+		for {
+			page, err := repo.ListCursor(ctx, opts)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.Items), opts.PageSize)
+			items = append(items, page.Items...)
+			if page.NextCursor == "" {
+				return items
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+
+	t.Run("cursor", func(t *testing.T) {
+		items := collect(t, ListOptions{PageSize: 7})
+		require.Len(t, items, 25)
+		for i, item := range items {
+			require.Equal(t, uint(i+1), item.ID)
+		}
+	})
+
+	t.Run("cursor with duplicate sort values", func(t *testing.T) {
+		items := collect(t, ListOptions{PageSize: 4, SortBy: "views", Desc: true})
+		require.Len(t, items, 25)
+		for i := 1; i < len(items); i++ {
+			prev, cur := items[i-1], items[i]
+			require.True(t, prev.Views > cur.Views || (prev.Views == cur.Views && prev.ID > cur.ID))
+		}
+	})
+
+	t.Run("cursor by time with filter", func(t *testing.T) {
+		items := collect(t, ListOptions{PageSize: 5, SortBy: "Published", Filters: map[string]any{"author": "ann"}})
+		require.Len(t, items, 13)
+		for i := 1; i < len(items); i++ {
+			require.True(t, items[i].Published.After(items[i-1].Published))
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := repo.ListCursor(ctx, ListOptions{Cursor: "not a cursor"})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+
+		page, err := repo.ListCursor(ctx, ListOptions{PageSize: 5})
+		require.NoError(t, err)
+		_, err = repo.ListCursor(ctx, ListOptions{PageSize: 5, SortBy: "views", Cursor: page.NextCursor})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hibare/GoCommon/v2/pkg/crypto/hash"
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// DefaultScriptsTable is the table recording the SQL scripts applied by RunSQLFromFSWithOptions.
+const DefaultScriptsTable = "applied_scripts"
+
+// ScriptStatus is the state of a SQL script.
+type ScriptStatus string
+
+const (
+	// ScriptPending is a script that has not been applied yet, reported by dry runs.
+	ScriptPending ScriptStatus = "pending"
+
+	// ScriptApplied is a script executed by the call.
+	ScriptApplied ScriptStatus = "applied"
+
+	// ScriptSkipped is a script applied by an earlier call.
+	ScriptSkipped ScriptStatus = "skipped"
+)
+
+// ScriptOptions configures RunSQLFromDirectoryWithOptions and RunSQLFromFSWithOptions.
+type ScriptOptions struct {
+	// Table records applied scripts; defaults to DefaultScriptsTable.
+	Table string
+	// DisableTracking executes every script on every call without recording it.
+	DisableTracking bool
+	// DisableTransaction executes scripts outside of a transaction, e.g. for CREATE INDEX CONCURRENTLY.
+	DisableTransaction bool
+	// DryRun reports the scripts that would be applied without executing them.
+	DryRun bool
+}
+
+// ScriptResult reports what happened to a SQL script.
+type ScriptResult struct {
+	// Name is the slash separated path of the script relative to the scripts directory.
+	Name string
+	// Checksum is the hex encoded SHA-256 of the script content.
+	Checksum string
+	Status   ScriptStatus
+}
+
+// appliedScript is a row of the applied scripts table.
+type appliedScript struct {
+	Name      string    `gorm:"primaryKey;size:255"`
+	Checksum  string    `gorm:"size:64;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// RunSQLFromDirectoryWithOptions executes the .sql files found in dir in alphabetical order.
+// By default each script runs in its own transaction and is recorded with its checksum, so later calls
+// skip it; a recorded script whose content changed fails with errors.ErrChecksumMismatch.
+func (d *DB) RunSQLFromDirectoryWithOptions(dir string, opts ScriptOptions) ([]ScriptResult, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	return d.RunSQLFromFSWithOptions(os.DirFS(dir), ".", opts)
+}
+
+// RunSQLFromFSWithOptions is RunSQLFromDirectoryWithOptions for an embedded filesystem directory.
+func (d *DB) RunSQLFromFSWithOptions(fsys fs.FS, dir string, opts ScriptOptions) ([]ScriptResult, error) {
+	if opts.Table == "" {
+		opts.Table = DefaultScriptsTable
+	}
+
+	files, err := listSQLFiles(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]string)
+	if !opts.DisableTracking {
+		applied, err = d.appliedScripts(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hasher := hash.NewSHA256Hasher()
+	results := make([]ScriptResult, 0, len(files))
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return results, err
+		}
+
+		sql := strings.TrimSpace(string(content))
+		if sql == "" {
+			continue
+		}
+
+		checksum, err := hasher.HashString(sql)
+		if err != nil {
+			return results, err
+		}
+
+		name := strings.TrimPrefix(file, strings.TrimSuffix(dir, "/")+"/")
+		result := ScriptResult{Name: name, Checksum: checksum, Status: ScriptApplied}
+
+		if previous, ok := applied[name]; ok {
+			if previous != checksum {
+				return results, fmt.Errorf("%w: script %s changed after it was applied", commonErrors.ErrChecksumMismatch, name)
+			}
+			result.Status = ScriptSkipped
+			results = append(results, result)
+			continue
+		}
+
+		if opts.DryRun {
+			result.Status = ScriptPending
+			results = append(results, result)
+			continue
+		}
+
+		if err := d.applyScript(sql, result, opts); err != nil {
+			return results, fmt.Errorf("failed to run script %s: %w", name, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// appliedScripts returns the checksums of the recorded scripts by name, creating the table unless in a dry run.
+// It reads from the primary, since a lagging replica could miss recently applied scripts.
+func (d *DB) appliedScripts(opts ScriptOptions) (map[string]string, error) {
+	applied := make(map[string]string)
+	primary := d.DB.WithContext(ReadFromPrimary(context.Background()))
+
+	if opts.DryRun {
+		if !primary.Migrator().HasTable(opts.Table) {
+			return applied, nil
+		}
+	} else if err := primary.Table(opts.Table).AutoMigrate(&appliedScript{}); err != nil {
+		return nil, fmt.Errorf("failed to create scripts table: %w", err)
+	}
+
+	var rows []appliedScript
+	if err := primary.Table(opts.Table).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read scripts table: %w", err)
+	}
+	for _, row := range rows {
+		applied[row.Name] = row.Checksum
+	}
+
+	return applied, nil
+}
+
+// applyScript executes the statements of a script one by one, for drivers rejecting multi-statement strings,
+// and records it, atomically unless transactions are disabled.
+func (d *DB) applyScript(sql string, result ScriptResult, opts ScriptOptions) error {
+	statements, err := splitStatements(d.config.DBType, sql)
+	if err != nil {
+		return err
+	}
+
+	run := func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if opts.DisableTracking {
+			return nil
+		}
+		return tx.Table(opts.Table).Create(&appliedScript{
+			Name:      result.Name,
+			Checksum:  result.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	}
+
+	if opts.DisableTransaction {
+		return run(d.DB)
+	}
+	return d.DB.Transaction(run)
+}
+
+// listSQLFiles returns the .sql files below dir in alphabetical order.
+func listSQLFiles(fsys fs.FS, dir string) ([]string, error) {
+	var files []string
+
+	err := fs.WalkDir(fsys, dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.EqualFold(path.Ext(entry.Name()), ".sql") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort files alphabetically for consistent execution order
+	sort.Strings(files)
+
+	return files, nil
+}
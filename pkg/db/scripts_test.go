@@ -0,0 +1,119 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_RunSQLFromFSWithOptions(t *testing.T) {
+	newDB := func(t *testing.T) *DB {
+		d := newTestSQLiteDB(t)
+		require.NoError(t, d.Migrate())
+		return d
+	}
+	countRows := func(t *testing.T, d *DB) int64 {
+		var count int64
+		require.NoError(t, d.DB.Table("tests").Count(&count).Error)
+		return count
+	}
+
+	t.Run("applies once", func(t *testing.T) {
+		d := newDB(t)
+
+		results, err := d.RunSQLFromDirectoryWithOptions("test_data/sql_scripts", ScriptOptions{})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Equal(t, "01_create_view.sql", results[0].Name)
+		require.Equal(t, ScriptApplied, results[0].Status)
+		require.Len(t, results[0].Checksum, 64)
+		require.True(t, d.DB.Migrator().HasTable(DefaultScriptsTable))
+
+		results, err = d.RunSQLFromDirectoryWithOptions("test_data/sql_scripts", ScriptOptions{})
+		require.NoError(t, err)
+		require.Equal(t, ScriptSkipped, results[0].Status)
+		require.Equal(t, ScriptSkipped, results[1].Status)
+		require.Equal(t, int64(1), countRows(t, d))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		d := newDB(t)
+		fsys := fstest.MapFS{
+			"seed/01_a.sql": {Data: []byte("INSERT INTO tests (name) VALUES ('a');")},
+			"seed/02_b.sql": {Data: []byte("INSERT INTO tests (name) VALUES ('b');")},
+		}
+
+		results, err := d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, []ScriptStatus{ScriptPending, ScriptPending}, []ScriptStatus{results[0].Status, results[1].Status})
+		require.False(t, d.DB.Migrator().HasTable(DefaultScriptsTable))
+		require.Zero(t, countRows(t, d))
+
+		_, err = d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{Table: "seed_scripts"})
+		require.NoError(t, err)
+		fsys["seed/03_c.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO tests (name) VALUES ('c');")}
+
+		results, err = d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{Table: "seed_scripts", DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, "03_c.sql", results[2].Name)
+		require.Equal(t, ScriptPending, results[2].Status)
+		require.Equal(t, int64(2), countRows(t, d))
+	})
+
+	t.Run("checksum drift", func(t *testing.T) {
+		d := newDB(t)
+		fsys := fstest.MapFS{"seed/01_a.sql": {Data: []byte("INSERT INTO tests (name) VALUES ('a');")}}
+
+		_, err := d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{})
+		require.NoError(t, err)
+
+		fsys["seed/01_a.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO tests (name) VALUES ('changed');")}
+		_, err = d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{})
+		require.ErrorIs(t, err, commonErrors.ErrChecksumMismatch)
+		require.Equal(t, int64(1), countRows(t, d))
+	})
+
+	t.Run("failed script is rolled back", func(t *testing.T) {
+		d := newDB(t)
+		fsys := fstest.MapFS{
+			"seed/01_bad.sql": {Data: []byte("INSERT INTO tests (name) VALUES ('partial'); INSERT INTO missing_table VALUES (1);")},
+		}
+
+		_, err := d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{})
+		require.Error(t, err)
+		require.Zero(t, countRows(t, d))
+
+		var recorded int64
+		require.NoError(t, d.DB.Table(DefaultScriptsTable).Count(&recorded).Error)
+		require.Zero(t, recorded)
+	})
+
+	t.Run("untracked reruns", func(t *testing.T) {
+		d := newDB(t)
+		fsys := fstest.MapFS{"seed/01_a.sql": {Data: []byte("INSERT INTO tests (name) VALUES ('a');")}}
+
+		require.NoError(t, d.RunSQLFromFS(fsys, "seed"))
+		require.NoError(t, d.RunSQLFromFS(fsys, "seed"))
+		require.Equal(t, int64(2), countRows(t, d))
+		require.False(t, d.DB.Migrator().HasTable(DefaultScriptsTable))
+	})
+	t.Run("statements are split", func(t *testing.T) {
+		d := newDB(t)
+		script := `CREATE TABLE audit (name TEXT);
+CREATE TRIGGER tests_audit AFTER INSERT ON tests BEGIN
+  INSERT INTO audit (name) VALUES (NEW.name);
+END;
+-- semicolons in literals; are not separators
+INSERT INTO tests (name) VALUES ('a;b');`
+		fsys := fstest.MapFS{"seed/01_a.sql": {Data: []byte(script)}}
+
+		_, err := d.RunSQLFromFSWithOptions(fsys, "seed", ScriptOptions{})
+		require.NoError(t, err)
+
+		var names []string
+		require.NoError(t, d.DB.Table("audit").Pluck("name", &names).Error)
+		require.Equal(t, []string{"a;b"}, names)
+	})
+}
@@ -0,0 +1,303 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SQLDialect selects the lexical rules used by SplitSQLStatements.
+type SQLDialect string
+
+const (
+	// DialectPostgres supports dollar-quoted bodies ($$ or $tag$), E'...' escape strings and nested block comments.
+	DialectPostgres SQLDialect = "postgres"
+
+	// DialectMySQL supports backtick identifiers, backslash escapes, # comments and DELIMITER lines.
+	DialectMySQL SQLDialect = "mysql"
+
+	// DialectSQLite supports backtick and [bracket] identifiers and CREATE TRIGGER ... BEGIN ... END bodies.
+	DialectSQLite SQLDialect = "sqlite"
+)
+
+// ErrInvalidSQL is returned when a string literal, identifier or comment is not terminated.
+var ErrInvalidSQL = errors.New("invalid sql")
+
+// dialecter is implemented by the database types whose SQL can be split into statements.
+type dialecter interface {
+	Dialect() SQLDialect
+}
+
+// SplitSQLStatements splits a SQL script into statements without their terminating semicolon,
+// ignoring semicolons in string literals, quoted identifiers, comments and dialect-specific bodies.
+// Statements made of comments only are dropped.
+func SplitSQLStatements(sql string, dialect SQLDialect) ([]string, error) {
+	s := sqlSplitter{sql: sql, dialect: dialect, delimiter: ";"}
+	return s.split()
+}
+
+// splitStatements splits sql with the dialect of dbType, or returns it whole when the dialect is unknown.
+func splitStatements(dbType Database, sql string) ([]string, error) {
+	d, ok := dbType.(dialecter)
+	if !ok {
+		return []string{sql}, nil
+	}
+	return SplitSQLStatements(sql, d.Dialect())
+}
+
+type sqlSplitter struct {
+	sql       string
+	dialect   SQLDialect
+	delimiter string
+
+	pos        int
+	start      int
+	hasCode    bool
+	statements []string
+
+	// head holds the first words of the statement, up to the TRIGGER keyword of a SQLite CREATE TRIGGER.
+	head []string
+	// trigger is set in SQLite CREATE TRIGGER statements, where depth counts the open BEGIN and CASE blocks.
+	trigger bool
+	depth   int
+}
+
+func (s *sqlSplitter) split() ([]string, error) {
+	for s.pos < len(s.sql) {
+		if !s.hasCode && s.dialect == DialectMySQL && s.readDelimiterCommand() {
+			continue
+		}
+
+		rest := s.sql[s.pos:]
+		c := rest[0]
+
+		switch {
+		case strings.HasPrefix(rest, s.delimiter):
+			if s.trigger && s.depth > 0 {
+				s.pos++
+				continue
+			}
+			s.emit(s.pos)
+			s.pos += len(s.delimiter)
+			s.start = s.pos
+			continue
+
+		case strings.HasPrefix(rest, "--"), c == '#' && s.dialect == DialectMySQL:
+			s.skipLineComment()
+			continue
+
+		case strings.HasPrefix(rest, "/*"):
+			// MySQL executes the content of /*! ... */ comments.
+			if s.dialect == DialectMySQL && strings.HasPrefix(rest, "/*!") {
+				s.hasCode = true
+			}
+			if err := s.skipBlockComment(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		s.hasCode = s.hasCode || !isSpace(c)
+
+		var err error
+		switch {
+		case c == '\'':
+			err = s.skipQuoted('\'', s.dialect == DialectMySQL || s.isEscapeString())
+		case c == '"':
+			err = s.skipQuoted('"', s.dialect == DialectMySQL)
+		case c == '`' && s.dialect != DialectPostgres:
+			err = s.skipQuoted('`', false)
+		case c == '[' && s.dialect == DialectSQLite:
+			err = s.skipUntil("]", "identifier")
+		case c == '$' && s.dialect == DialectPostgres:
+			err = s.skipDollarQuoted()
+		case isIdentChar(c) && s.dialect == DialectSQLite:
+			s.readWord()
+		default:
+			s.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.emit(len(s.sql))
+	return s.statements, nil
+}
+
+// emit records the statement ending at end unless it only holds whitespace and comments.
+func (s *sqlSplitter) emit(end int) {
+	if s.hasCode {
+		s.statements = append(s.statements, strings.TrimSpace(s.sql[s.start:end]))
+	}
+	s.hasCode = false
+	s.head = s.head[:0]
+	s.trigger = false
+	s.depth = 0
+}
+
+// readDelimiterCommand handles a MySQL client DELIMITER line, which is not sent to the server.
+func (s *sqlSplitter) readDelimiterCommand() bool {
+	i := s.pos
+	for i < len(s.sql) && isSpace(s.sql[i]) {
+		i++
+	}
+	const command = "delimiter "
+	if len(s.sql)-i < len(command) || !strings.EqualFold(s.sql[i:i+len(command)], command) {
+		return false
+	}
+
+	end := strings.IndexByte(s.sql[i:], '\n')
+	if end < 0 {
+		end = len(s.sql)
+	} else {
+		end += i
+	}
+	if delimiter := strings.TrimSpace(s.sql[i+len(command) : end]); delimiter != "" {
+		s.delimiter = delimiter
+	}
+	s.pos = end
+	s.start = end
+	return true
+}
+
+// readWord reads a keyword or identifier, tracking the nesting of BEGIN ... END and CASE ... END blocks
+// in SQLite CREATE TRIGGER statements, whose body holds semicolons.
+func (s *sqlSplitter) readWord() {
+	end := s.pos
+	for end < len(s.sql) && isIdentChar(s.sql[end]) {
+		end++
+	}
+	word := strings.ToUpper(s.sql[s.pos:end])
+	s.pos = end
+
+	if len(s.head) < 3 && !s.trigger {
+		s.head = append(s.head, word)
+		s.trigger = isCreateTrigger(s.head)
+		return
+	}
+	if !s.trigger {
+		return
+	}
+	switch word {
+	case "BEGIN", "CASE":
+		s.depth++
+	case "END":
+		s.depth = max(s.depth-1, 0)
+	}
+}
+
+// isCreateTrigger reports whether the first words of a statement are CREATE [TEMP|TEMPORARY] TRIGGER.
+func isCreateTrigger(head []string) bool {
+	switch len(head) {
+	case 2:
+		return head[0] == "CREATE" && head[1] == "TRIGGER"
+	case 3:
+		return head[0] == "CREATE" && (head[1] == "TEMP" || head[1] == "TEMPORARY") && head[2] == "TRIGGER"
+	}
+	return false
+}
+
+func (s *sqlSplitter) skipLineComment() {
+	end := strings.IndexByte(s.sql[s.pos:], '\n')
+	if end < 0 {
+		s.pos = len(s.sql)
+		return
+	}
+	s.pos += end + 1
+}
+
+// skipBlockComment skips a comment, which nests in Postgres.
+func (s *sqlSplitter) skipBlockComment() error {
+	start := s.pos
+	depth := 0
+	for s.pos < len(s.sql) {
+		switch {
+		case strings.HasPrefix(s.sql[s.pos:], "/*") && (depth == 0 || s.dialect == DialectPostgres):
+			depth++
+			s.pos += 2
+		case strings.HasPrefix(s.sql[s.pos:], "*/"):
+			depth--
+			s.pos += 2
+			if depth == 0 {
+				return nil
+			}
+		default:
+			s.pos++
+		}
+	}
+	return fmt.Errorf("%w: unterminated comment at offset %d", ErrInvalidSQL, start)
+}
+
+// skipQuoted skips a literal or identifier closed by quote, where a doubled quote is escaped
+// and, if backslash is set, a backslash escapes the next character.
+func (s *sqlSplitter) skipQuoted(quote byte, backslash bool) error {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.sql) {
+		c := s.sql[s.pos]
+		switch {
+		case backslash && c == '\\':
+			s.pos += 2
+		case c == quote && s.pos+1 < len(s.sql) && s.sql[s.pos+1] == quote:
+			s.pos += 2
+		case c == quote:
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return fmt.Errorf("%w: unterminated %c quote at offset %d", ErrInvalidSQL, quote, start)
+}
+
+// isEscapeString reports whether the quote at pos starts a Postgres escape string (E'...').
+func (s *sqlSplitter) isEscapeString() bool {
+	if s.dialect != DialectPostgres || s.pos == 0 || (s.sql[s.pos-1] != 'E' && s.sql[s.pos-1] != 'e') {
+		return false
+	}
+	return s.pos == 1 || !isIdentChar(s.sql[s.pos-2])
+}
+
+func (s *sqlSplitter) skipUntil(end, what string) error {
+	start := s.pos
+	i := strings.Index(s.sql[s.pos+1:], end)
+	if i < 0 {
+		return fmt.Errorf("%w: unterminated %s at offset %d", ErrInvalidSQL, what, start)
+	}
+	s.pos += 1 + i + len(end)
+	return nil
+}
+
+// skipDollarQuoted skips a Postgres $tag$ ... $tag$ body; other dollar signs, e.g. $1 parameters, are code.
+func (s *sqlSplitter) skipDollarQuoted() error {
+	if s.pos > 0 && isIdentChar(s.sql[s.pos-1]) {
+		s.pos++
+		return nil
+	}
+
+	i := s.pos + 1
+	for i < len(s.sql) && isIdentChar(s.sql[i]) && (i > s.pos+1 || !isDigit(s.sql[i])) {
+		i++
+	}
+	if i >= len(s.sql) || s.sql[i] != '$' {
+		s.pos++
+		return nil
+	}
+
+	tag := s.sql[s.pos : i+1]
+	s.pos = i
+	return s.skipUntil(tag, "dollar-quoted string "+tag)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
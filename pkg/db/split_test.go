@@ -0,0 +1,138 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		sql     string
+		want    []string
+	}{
+		{
+			name:    "statements and comments",
+			dialect: DialectPostgres,
+			sql: `-- header; comment
+CREATE TABLE a (id INT); /* block; comment */
+INSERT INTO a VALUES (1);
+-- trailing comment`,
+			want: []string{
+				"-- header; comment\nCREATE TABLE a (id INT)",
+				"/* block; comment */\nINSERT INTO a VALUES (1)",
+			},
+		},
+		{
+			name:    "string literals and identifiers",
+			dialect: DialectPostgres,
+			sql:     `INSERT INTO "we;ird" VALUES ('a;b', 'it''s; fine', E'esc\';aped'); SELECT 1`,
+			want:    []string{`INSERT INTO "we;ird" VALUES ('a;b', 'it''s; fine', E'esc\';aped')`, "SELECT 1"},
+		},
+		{
+			name:    "backslash is literal in standard postgres strings",
+			dialect: DialectPostgres,
+			sql:     `SELECT 'C:\'; SELECT 2`,
+			want:    []string{`SELECT 'C:\'`, "SELECT 2"},
+		},
+		{
+			name:    "dollar quoting",
+			dialect: DialectPostgres,
+			sql: `CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DO $body$ BEGIN PERFORM 1; END $body$;
+SELECT $1::int;`,
+			want: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at := now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"DO $body$ BEGIN PERFORM 1; END $body$",
+				"SELECT $1::int",
+			},
+		},
+		{
+			name:    "nested postgres comments",
+			dialect: DialectPostgres,
+			sql:     "/* outer /* inner; */ still; comment */ SELECT 1; SELECT 2",
+			want:    []string{"/* outer /* inner; */ still; comment */ SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "mysql quoting and comments",
+			dialect: DialectMySQL,
+			sql:     "# comment; here\nINSERT INTO `t;1` VALUES ('a\\';b', \"c;d\"); /*!40101 SET NAMES utf8 */;",
+			want:    []string{"# comment; here\nINSERT INTO `t;1` VALUES ('a\\';b', \"c;d\")", "/*!40101 SET NAMES utf8 */"},
+		},
+		{
+			name:    "mysql delimiter",
+			dialect: DialectMySQL,
+			sql: `DELIMITER //
+CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END//
+DELIMITER ;
+CALL p();`,
+			want: []string{"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END", "CALL p()"},
+		},
+		{
+			name:    "sqlite trigger",
+			dialect: DialectSQLite,
+			sql: `CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN
+  UPDATE a SET n = n + 1;
+  DELETE FROM b;
+END;
+SELECT [col;1] FROM a;`,
+			want: []string{
+				"CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN\n  UPDATE a SET n = n + 1;\n  DELETE FROM b;\nEND",
+				"SELECT [col;1] FROM a",
+			},
+		},
+		{
+			name:    "sqlite trigger with case expressions",
+			dialect: DialectSQLite,
+			sql:     "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET x = CASE WHEN NEW.y THEN 1 ELSE 2 END; INSERT INTO c VALUES (1); END; SELECT 1;",
+			want: []string{
+				"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET x = CASE WHEN NEW.y THEN 1 ELSE 2 END; INSERT INTO c VALUES (1); END",
+				"SELECT 1",
+			},
+		},
+		{
+			name:    "sqlite trigger keywords in literals",
+			dialect: DialectSQLite,
+			sql:     "CREATE TRIGGER t BEFORE DELETE ON a WHEN CASE old.k WHEN 'end' THEN 1 END BEGIN SELECT 'begin;'; -- end;\nSELECT \"end\"; END; SELECT 2",
+			want: []string{
+				"CREATE TRIGGER t BEFORE DELETE ON a WHEN CASE old.k WHEN 'end' THEN 1 END BEGIN SELECT 'begin;'; -- end;\nSELECT \"end\"; END",
+				"SELECT 2",
+			},
+		},
+		{
+			name:    "comments only",
+			dialect: DialectSQLite,
+			sql:     "-- nothing\n/* here */;\n",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitSQLStatements(tt.sql, tt.dialect)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitSQLStatements_Unterminated(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT 'open",
+		`SELECT "open`,
+		"SELECT 1 /* open",
+		"DO $$ BEGIN",
+	} {
+		t.Run(sql, func(t *testing.T) {
+			_, err := SplitSQLStatements(sql, DialectPostgres)
+			require.ErrorIs(t, err, ErrInvalidSQL)
+		})
+	}
+}
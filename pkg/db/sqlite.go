@@ -1,12 +1,10 @@
 package db
 
 import (
-	"errors"
 	"fmt"
 
-	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	migrateSQLite "github.com/golang-migrate/migrate/v4/database/sqlite"
-	"github.com/johejo/golang-migrate-extra/source/iofs"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -16,34 +14,49 @@ type SQLiteDatabase struct{}
 
 // Open opens a database connection.
 func (s *SQLiteDatabase) Open(config DatabaseConfig) (*gorm.DB, error) {
-	return gorm.Open(sqlite.Open(config.DSN), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(config.DSN), config.gormConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.configurePool(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
 }
 
 // Migrate migrates the database.
 func (s *SQLiteDatabase) Migrate(db *gorm.DB, config DatabaseConfig) error {
+	return migrateUp(s, db, config)
+}
+
+// MigrationDriver returns the golang-migrate driver for the database.
+func (s *SQLiteDatabase) MigrationDriver(db *gorm.DB) (string, database.Driver, error) {
 	sqlDB, err := db.DB()
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	driver, err := migrateSQLite.WithInstance(sqlDB, &migrateSQLite.Config{})
 	if err != nil {
-		return fmt.Errorf("could not create sqlite driver: %w", err)
+		return "", nil, fmt.Errorf("could not create sqlite driver: %w", err)
 	}
 
-	source, err := iofs.New(config.MigrationsFS, config.MigrationsPath)
-	if err != nil {
-		return fmt.Errorf("could not create source: %w", err)
-	}
-
-	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
-	if err != nil {
-		return fmt.Errorf("could not create migrate instance: %w", err)
-	}
+	return "sqlite", sharedDBDriver{Driver: driver}, nil
+}
 
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("could not run migrations: %w", err)
-	}
+// sharedDBDriver is a driver working on the shared *sql.DB, which closing the driver must not close.
+type sharedDBDriver struct {
+	database.Driver
+}
 
+// Close leaves the shared *sql.DB open.
+func (sharedDBDriver) Close() error {
 	return nil
 }
+
+// Dialect returns the SQL dialect used to split scripts into statements.
+func (s *SQLiteDatabase) Dialect() SQLDialect {
+	return DialectSQLite
+}
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN slug TEXT;
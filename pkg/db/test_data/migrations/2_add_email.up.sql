@@ -0,0 +1 @@
+ALTER TABLE tests ADD COLUMN email TEXT;
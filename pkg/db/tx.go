@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTxMaxRetries is the number of retries of a transaction failing with a serialization error.
+	DefaultTxMaxRetries = 3
+
+	// DefaultTxRetryBackoff is the initial backoff between transaction attempts; it doubles after each retry.
+	DefaultTxRetryBackoff = 10 * time.Millisecond
+)
+
+// TxOptions configures WithTx. Isolation and ReadOnly only apply to the outermost transaction.
+type TxOptions struct {
+	// Isolation defaults to the isolation level of the database.
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxRetries defaults to DefaultTxMaxRetries; a negative value disables retries.
+	MaxRetries int
+	// RetryBackoff defaults to DefaultTxRetryBackoff. The wait is jittered between zero and the backoff.
+	RetryBackoff time.Duration
+}
+
+// txContextKey carries the current transaction in a context.
+type txContextKey struct{}
+
+// txContextValue is a transaction with the DB it was started on, so other DBs do not join it.
+type txContextValue struct {
+	db *DB
+	tx *gorm.DB
+}
+
+// TxFromContext returns the transaction of d started by WithTx that ctx belongs to.
+// Transactions of other DBs carried by ctx are ignored.
+func (d *DB) TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	value, ok := ctx.Value(txContextKey{}).(txContextValue)
+	if !ok || value.db != d {
+		return nil, false
+	}
+	return value.tx, true
+}
+
+// ContextWithTx returns a context carrying tx, a transaction of d, so WithTx and Repository calls of d made
+// with it join tx, e.g. to roll back everything a test did.
+func (d *DB) ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, txContextValue{db: d, tx: tx})
+}
+
+// WithTx runs fn in a transaction, committing when it returns nil and rolling back otherwise.
+// The context passed to fn carries the transaction, so a nested WithTx call of d runs in a savepoint of it,
+// while other DBs start their own transaction.
+// The outermost transaction is retried on serialization failures and deadlocks (Postgres 40001/40P01,
+// MySQL 1213) and on SQLITE_BUSY, so fn must be safe to run several times.
+func (d *DB) WithTx(ctx context.Context, fn func(ctx context.Context, tx *gorm.DB) error, opts TxOptions) error {
+	if parent, ok := d.TxFromContext(ctx); ok {
+		// gorm uses a savepoint when a transaction is started from a transaction.
+		return parent.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(d.ContextWithTx(ctx, tx), tx)
+		})
+	}
+
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultTxMaxRetries
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultTxRetryBackoff
+	}
+
+	txOptions := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	backoff := opts.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(d.ContextWithTx(ctx, tx), tx)
+		}, txOptions)
+		if err == nil || !isRetryableTxError(err) || attempt >= opts.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rand.N(backoff) + 1):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryableTxError reports whether err is a transient conflict that succeeds when the transaction is retried.
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
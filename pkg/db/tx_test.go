@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestDB_WithTx(t *testing.T) {
+	d := newTestSQLiteDB(t)
+	require.NoError(t, d.Migrate())
+	countRows := func(t *testing.T) int64 {
+		var count int64
+		require.NoError(t, d.DB.Table("tests").Count(&count).Error)
+		return count
+	}
+	insert := func(tx *gorm.DB, name string) error {
+		return tx.Exec("INSERT INTO tests (name) VALUES (?)", name).Error
+	}
+
+	t.Run("commit and rollback", func(t *testing.T) {
+		require.NoError(t, d.WithTx(t.Context(), func(_ context.Context, tx *gorm.DB) error {
+			return insert(tx, "committed")
+		}, TxOptions{}))
+
+		errFailed := errors.New("failed")
+		err := d.WithTx(t.Context(), func(_ context.Context, tx *gorm.DB) error {
+			require.NoError(t, insert(tx, "rolled back"))
+			return errFailed
+		}, TxOptions{})
+		require.ErrorIs(t, err, errFailed)
+		require.Equal(t, int64(1), countRows(t))
+	})
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		errInner := errors.New("inner failed")
+		before := countRows(t)
+
+		err := d.WithTx(t.Context(), func(ctx context.Context, tx *gorm.DB) error {
+			fromCtx, ok := d.TxFromContext(ctx)
+			require.True(t, ok)
+			require.Same(t, tx, fromCtx)
+
+			require.NoError(t, insert(tx, "outer"))
+			innerErr := d.WithTx(ctx, func(_ context.Context, inner *gorm.DB) error {
+				require.NoError(t, insert(inner, "inner"))
+				return errInner
+			}, TxOptions{})
+			require.ErrorIs(t, innerErr, errInner)
+			return nil
+		}, TxOptions{})
+		require.NoError(t, err)
+		require.Equal(t, before+1, countRows(t))
+	})
+
+	t.Run("other DB does not join", func(t *testing.T) {
+		other := newTestSQLiteDB(t)
+		require.NoError(t, other.Migrate())
+		errFailed := errors.New("failed")
+
+		err := d.WithTx(t.Context(), func(ctx context.Context, _ *gorm.DB) error {
+			_, ok := other.TxFromContext(ctx)
+			require.False(t, ok)
+
+			// Joining d's transaction would write the row to d and roll it back.
+			require.NoError(t, other.WithTx(ctx, func(_ context.Context, tx *gorm.DB) error {
+				return insert(tx, "other")
+			}, TxOptions{}))
+			return errFailed
+		}, TxOptions{})
+		require.ErrorIs(t, err, errFailed)
+
+		var count int64
+		require.NoError(t, other.DB.Table("tests").Where("name = ?", "other").Count(&count).Error)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("retries serialization failures", func(t *testing.T) {
+		attempts := 0
+		err := d.WithTx(t.Context(), func(_ context.Context, _ *gorm.DB) error {
+			attempts++
+			if attempts < 3 {
+				return &pgconn.PgError{Code: "40001"}
+			}
+			return nil
+		}, TxOptions{RetryBackoff: time.Millisecond})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		attempts := 0
+		err := d.WithTx(t.Context(), func(_ context.Context, _ *gorm.DB) error {
+			attempts++
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}, TxOptions{MaxRetries: 2, RetryBackoff: time.Millisecond})
+		require.Error(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		attempts := 0
+		err := d.WithTx(t.Context(), func(_ context.Context, _ *gorm.DB) error {
+			attempts++
+			return &pgconn.PgError{Code: "40P01"}
+		}, TxOptions{MaxRetries: -1})
+		require.Error(t, err)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		err := d.WithTx(ctx, func(_ context.Context, _ *gorm.DB) error {
+			cancel()
+			return &pgconn.PgError{Code: "40001"}
+		}, TxOptions{RetryBackoff: time.Hour})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	require.True(t, isRetryableTxError(&pgconn.PgError{Code: "40001"}))
+	require.True(t, isRetryableTxError(&pgconn.PgError{Code: "40P01"}))
+	require.False(t, isRetryableTxError(&pgconn.PgError{Code: "23505"}))
+	require.True(t, isRetryableTxError(&mysql.MySQLError{Number: 1213}))
+	require.True(t, isRetryableTxError(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	require.False(t, isRetryableTxError(sqlite3.Error{Code: sqlite3.ErrConstraint}))
+	require.False(t, isRetryableTxError(errors.New("other")))
+}
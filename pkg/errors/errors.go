@@ -27,6 +27,9 @@ var (
 
 	// ErrRecordNotFound indicates a record not found error.
 	ErrRecordNotFound = errors.New("record not found")
+
+	// ErrTooManyRequests indicates that a rate limit was exceeded.
+	ErrTooManyRequests = errors.New("too many requests")
 )
 
 // Error represents an error with a code and message.
@@ -0,0 +1,408 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveFormat is the container format produced by the archive helpers.
+type ArchiveFormat string
+
+const (
+	// ArchiveFormatZip produces a deflate-compressed zip archive.
+	ArchiveFormatZip ArchiveFormat = "zip"
+
+	// ArchiveFormatTar produces an uncompressed tarball.
+	ArchiveFormatTar ArchiveFormat = "tar"
+
+	// ArchiveFormatTarGz produces a gzip-compressed tarball.
+	ArchiveFormatTarGz ArchiveFormat = "tar.gz"
+
+	// ArchiveFormatTarZst produces a zstd-compressed tarball.
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+)
+
+// Extension returns the file extension for the format, including the leading dot.
+func (f ArchiveFormat) Extension() string {
+	return "." + string(f)
+}
+
+func (f ArchiveFormat) isValid() bool {
+	switch f {
+	case ArchiveFormatZip, ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTarZst:
+		return true
+	default:
+		return false
+	}
+}
+
+// CompressionLevel selects the speed/size trade-off of the archive compression.
+type CompressionLevel int
+
+const (
+	// CompressionDefault uses the default level of the format's compressor.
+	CompressionDefault CompressionLevel = iota
+
+	// CompressionNone stores entries without compression (zstd falls back to its fastest level).
+	CompressionNone
+
+	// CompressionFastest favours speed over size.
+	CompressionFastest
+
+	// CompressionBest favours size over speed.
+	CompressionBest
+)
+
+// flateLevel maps the level to a compress/flate (and compress/gzip) level.
+func (l CompressionLevel) flateLevel() int {
+	switch l {
+	case CompressionNone:
+		return flate.NoCompression
+	case CompressionFastest:
+		return flate.BestSpeed
+	case CompressionBest:
+		return flate.BestCompression
+	default:
+		return flate.DefaultCompression
+	}
+}
+
+// zstdLevel maps the level to a zstd encoder level.
+func (l CompressionLevel) zstdLevel() zstd.EncoderLevel {
+	switch l {
+	case CompressionNone, CompressionFastest:
+		return zstd.SpeedFastest
+	case CompressionBest:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// ArchiveOptions configures ArchiveDirTo.
+type ArchiveOptions struct {
+	// Format is the archive format; defaults to ArchiveFormatZip.
+	Format ArchiveFormat
+	// Exclude skips files and dirs whose base name matches any of the patterns.
+	Exclude []*regexp.Regexp
+	// CompressionLevel is ignored for ArchiveFormatTar.
+	CompressionLevel CompressionLevel
+}
+
+// ArchiveDirResponse represents the result of archiving a directory.
+type ArchiveDirResponse struct {
+	ArchivePath  string
+	TotalFiles   int
+	TotalDirs    int
+	SuccessFiles int
+	FailedFiles  map[string]error
+}
+
+// ArchiveDir creates a zip archive of the specified directory, excluding files/dirs matching the exclude patterns.
+func ArchiveDir(dirPath string, exclude []*regexp.Regexp) (ArchiveDirResponse, error) {
+	return ArchiveDirAs(dirPath, ArchiveFormatZip, exclude)
+}
+
+// ArchiveDirAs creates an archive of the specified directory in the given format, excluding files/dirs matching the exclude patterns.
+// The archive is written to os.TempDir() as <dirname>.<ext>; use ArchiveDirTo to stream it elsewhere.
+func ArchiveDirAs(dirPath string, format ArchiveFormat, exclude []*regexp.Regexp) (ArchiveDirResponse, error) {
+	if !format.isValid() {
+		return ArchiveDirResponse{}, fmt.Errorf("%w: %s", ErrUnsupportedArchiveFormat, format)
+	}
+
+	dirPath = filepath.Clean(dirPath)
+	archivePath := filepath.Join(os.TempDir(), filepath.Base(dirPath)+format.Extension())
+
+	archiveFile, err := NewAtomicWriter(archivePath, AtomicWriteOptions{})
+	if err != nil {
+		return ArchiveDirResponse{}, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer func() {
+		_ = archiveFile.Close()
+	}()
+
+	resp, err := ArchiveDirTo(context.Background(), dirPath, archiveFile, ArchiveOptions{
+		Format:  format,
+		Exclude: exclude,
+	})
+	resp.ArchivePath = archivePath
+	if err == nil {
+		if err = archiveFile.Commit(); err != nil {
+			err = fmt.Errorf("failed to write archive file: %w", err)
+		}
+	}
+
+	return resp, err
+}
+
+// ArchiveDirTo streams an archive of the specified directory into w without staging it on disk.
+// Tarballs preserve permissions, modification times, symlinks and empty directories.
+// The walk stops as soon as ctx is cancelled, leaving a truncated archive in w.
+func ArchiveDirTo(ctx context.Context, dirPath string, w io.Writer, opts ArchiveOptions) (ArchiveDirResponse, error) {
+	if opts.Format == "" {
+		opts.Format = ArchiveFormatZip
+	}
+	if !opts.Format.isValid() {
+		return ArchiveDirResponse{}, fmt.Errorf("%w: %s", ErrUnsupportedArchiveFormat, opts.Format)
+	}
+
+	dirPath = filepath.Clean(dirPath)
+
+	if opts.Format == ArchiveFormatZip {
+		return writeZip(ctx, w, dirPath, opts)
+	}
+
+	compressor, err := newCompressor(w, opts.Format, opts.CompressionLevel)
+	if err != nil {
+		return ArchiveDirResponse{}, err
+	}
+
+	resp, err := writeTar(ctx, compressor, dirPath, opts.Exclude)
+	if closeErr := compressor.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to finalize archive: %w", closeErr)
+	}
+
+	return resp, err
+}
+
+// nopWriteCloser wraps a writer that needs no finalization.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressor wraps w with the compression used by the tar based format.
+func newCompressor(w io.Writer, format ArchiveFormat, level CompressionLevel) (io.WriteCloser, error) {
+	switch format {
+	case ArchiveFormatTarGz:
+		gw, err := gzip.NewWriterLevel(w, level.flateLevel())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		}
+		return gw, nil
+	case ArchiveFormatTarZst:
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(level.zstdLevel()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		return zw, nil
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+// contextReader aborts reads once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// walkArchive walks dirPath honouring the exclude patterns and ctx, counts files and dirs,
+// and calls add for every entry below the root. An error returned by add aborts the walk.
+func walkArchive(
+	ctx context.Context,
+	dirPath string,
+	exclude []*regexp.Regexp,
+	add func(path, relPath string, info os.FileInfo, resp *ArchiveDirResponse) error,
+) (ArchiveDirResponse, error) {
+	resp := ArchiveDirResponse{
+		FailedFiles: make(map[string]error),
+	}
+
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk error at %s: %w", path, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if shouldExclude(info.Name(), exclude) {
+				slog.Info("Skipping dir", "name", info.Name(), "path", path)
+				return filepath.SkipDir
+			}
+			resp.TotalDirs++
+		} else {
+			if shouldExclude(info.Name(), exclude) {
+				slog.Info("Skipping file", "name", info.Name(), "path", path)
+				return nil
+			}
+			resp.TotalFiles++
+		}
+
+		relPath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			resp.FailedFiles[path] = fmt.Errorf("failed to get relative path: %w", err)
+			return nil
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		return add(path, relPath, info, &resp)
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return resp, err
+}
+
+// writeZip walks dirPath and writes its regular files as a zip archive to w.
+func writeZip(ctx context.Context, w io.Writer, dirPath string, opts ArchiveOptions) (ArchiveDirResponse, error) {
+	zipWriter := zip.NewWriter(w)
+
+	method := zip.Deflate
+	if opts.CompressionLevel == CompressionNone {
+		method = zip.Store
+	} else {
+		level := opts.CompressionLevel.flateLevel()
+		zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+
+	resp, err := walkArchive(ctx, dirPath, opts.Exclude, func(path, relPath string, info os.FileInfo, resp *ArchiveDirResponse) error {
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			resp.FailedFiles[path] = fmt.Errorf("failed to open file: %w", err)
+			return nil
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			resp.FailedFiles[path] = fmt.Errorf("failed to create zip header: %w", err)
+			return nil
+		}
+		header.Name = filepath.ToSlash(relPath)
+		header.Method = method
+
+		zh, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			resp.FailedFiles[path] = fmt.Errorf("failed to create zip header: %w", err)
+			return nil
+		}
+
+		if _, err := io.Copy(zh, contextReader{ctx: ctx, r: file}); err != nil {
+			resp.FailedFiles[path] = fmt.Errorf("failed to copy file to zip: %w", err)
+			return nil
+		}
+
+		resp.SuccessFiles++
+		return nil
+	})
+
+	if closeErr := zipWriter.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close zip writer: %w", closeErr)
+	}
+
+	return resp, err
+}
+
+// writeTar walks dirPath and writes its entries as a tarball to w.
+func writeTar(ctx context.Context, w io.Writer, dirPath string, exclude []*regexp.Regexp) (ArchiveDirResponse, error) {
+	tarWriter := tar.NewWriter(w)
+
+	resp, err := walkArchive(ctx, dirPath, exclude, func(path, relPath string, info os.FileInfo, resp *ArchiveDirResponse) error {
+		header, file, err := tarEntry(path, relPath, info)
+		if err != nil {
+			resp.FailedFiles[path] = err
+			return nil
+		}
+		if file != nil {
+			defer func() {
+				_ = file.Close()
+			}()
+		}
+
+		// Once a header is written a failure leaves the stream unusable, so abort the walk.
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %w", path, err)
+		}
+		if file == nil {
+			if !info.IsDir() {
+				resp.SuccessFiles++
+			}
+			return nil
+		}
+
+		if _, err := io.CopyN(tarWriter, contextReader{ctx: ctx, r: file}, header.Size); err != nil {
+			return fmt.Errorf("failed to copy %s to tar: %w", path, err)
+		}
+
+		resp.SuccessFiles++
+		return nil
+	})
+
+	if closeErr := tarWriter.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close tar writer: %w", closeErr)
+	}
+
+	return resp, err
+}
+
+// tarEntry builds the header for a directory, symlink or regular file and opens regular files for reading.
+// Other file types (devices, sockets, pipes) are rejected.
+func tarEntry(path, relPath string, info os.FileInfo) (*tar.Header, *os.File, error) {
+	var link string
+	mode := info.Mode()
+
+	switch {
+	case mode.IsDir(), mode.IsRegular():
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read symlink: %w", err)
+		}
+		link = target
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, mode.Type())
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create tar header: %w", err)
+	}
+	header.Name = filepath.ToSlash(relPath)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if !mode.IsRegular() {
+		return header, nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return header, file, nil
+}
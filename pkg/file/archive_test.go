@@ -0,0 +1,267 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// createArchiveFixture creates a directory with a file, an empty dir, a symlink and an excluded file.
+func createArchiveFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "fixture")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "empty"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "data.txt"), []byte("data"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "skip.log"), []byte("log"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join("sub", "data.txt"), filepath.Join(dir, "link")))
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "sub", "data.txt"), mtime, mtime))
+
+	return dir
+}
+
+// readTarHeaders decompresses the archive and returns its headers keyed by name.
+func readTarHeaders(t *testing.T, path string, format ArchiveFormat) map[string]*tar.Header {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = f.Close()
+	})
+
+	var r io.Reader = f
+	switch format {
+	case ArchiveFormatTarGz:
+		gr, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = gr
+	case ArchiveFormatTarZst:
+		zr, err := zstd.NewReader(f)
+		require.NoError(t, err)
+		t.Cleanup(zr.Close)
+		r = zr
+	}
+
+	headers := make(map[string]*tar.Header)
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		headers[h.Name] = h
+	}
+	return headers
+}
+
+func TestArchiveDirAs(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTarZst} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := createArchiveFixture(t)
+
+			resp, err := ArchiveDirAs(dir, format, []*regexp.Regexp{regexp.MustCompile(`\.log$`)})
+			t.Cleanup(func() {
+				_ = os.Remove(resp.ArchivePath)
+			})
+			require.NoError(t, err)
+			require.Equal(t, filepath.Join(os.TempDir(), "fixture"+format.Extension()), resp.ArchivePath)
+			require.Equal(t, 2, resp.TotalFiles)
+			require.Equal(t, 3, resp.TotalDirs)
+			require.Equal(t, 2, resp.SuccessFiles)
+			require.Empty(t, resp.FailedFiles)
+
+			headers := readTarHeaders(t, resp.ArchivePath, format)
+			require.Len(t, headers, 4)
+			require.NotContains(t, headers, "skip.log")
+
+			data := headers["sub/data.txt"]
+			require.NotNil(t, data)
+			require.Equal(t, int64(0o600), data.Mode&0o777)
+			require.True(t, data.ModTime.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+			link := headers["link"]
+			require.NotNil(t, link)
+			require.Equal(t, byte(tar.TypeSymlink), link.Typeflag)
+			require.Equal(t, filepath.Join("sub", "data.txt"), link.Linkname)
+
+			empty := headers["sub/empty/"]
+			require.NotNil(t, empty)
+			require.Equal(t, byte(tar.TypeDir), empty.Typeflag)
+		})
+	}
+
+	t.Run("zip", func(t *testing.T) {
+		dir := createArchiveFixture(t)
+
+		resp, err := ArchiveDirAs(dir, ArchiveFormatZip, nil)
+		t.Cleanup(func() {
+			_ = os.Remove(resp.ArchivePath)
+		})
+		require.NoError(t, err)
+		require.Equal(t, ".zip", filepath.Ext(resp.ArchivePath))
+	})
+
+	t.Run("extract from tar.gz", func(t *testing.T) {
+		dir := createArchiveFixture(t)
+
+		resp, err := ArchiveDirAs(dir, ArchiveFormatTarGz, nil)
+		t.Cleanup(func() {
+			_ = os.Remove(resp.ArchivePath)
+		})
+		require.NoError(t, err)
+
+		extracted, err := ExtractFileFromTarGz(resp.ArchivePath, "data.txt")
+		t.Cleanup(func() {
+			_ = os.Remove(extracted)
+		})
+		require.NoError(t, err)
+		content, err := os.ReadFile(extracted)
+		require.NoError(t, err)
+		require.Equal(t, "data", string(content))
+	})
+
+	t.Run("extract replaces a longer file", func(t *testing.T) {
+		dir := createArchiveFixture(t)
+
+		resp, err := ArchiveDirAs(dir, ArchiveFormatTarGz, nil)
+		t.Cleanup(func() {
+			_ = os.Remove(resp.ArchivePath)
+		})
+		require.NoError(t, err)
+
+		previous := filepath.Join(os.TempDir(), "data.txt")
+		require.NoError(t, os.WriteFile(previous, []byte("previous content"), 0o600))
+		extracted, err := ExtractFileFromTarGz(resp.ArchivePath, "data.txt")
+		t.Cleanup(func() {
+			_ = os.Remove(extracted)
+		})
+		require.NoError(t, err)
+		content, err := os.ReadFile(extracted)
+		require.NoError(t, err)
+		require.Equal(t, "data", string(content))
+	})
+
+	t.Run("keeps the previous archive on failure", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), filepath.Base(t.TempDir())+"-missing")
+		archivePath := filepath.Join(os.TempDir(), filepath.Base(dir)+ArchiveFormatTar.Extension())
+		require.NoError(t, os.WriteFile(archivePath, []byte("previous"), 0o600))
+		t.Cleanup(func() {
+			_ = os.Remove(archivePath)
+		})
+
+		_, err := ArchiveDirAs(dir, ArchiveFormatTar, nil)
+		require.Error(t, err)
+		content, err := os.ReadFile(archivePath)
+		require.NoError(t, err)
+		require.Equal(t, "previous", string(content))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := ArchiveDirAs(t.TempDir(), "rar", nil)
+		require.ErrorIs(t, err, ErrUnsupportedArchiveFormat)
+	})
+
+	t.Run("invalid directory", func(t *testing.T) {
+		resp, err := ArchiveDirAs("/tmp/does-not-exists", ArchiveFormatTar, nil)
+		t.Cleanup(func() {
+			_ = os.Remove(resp.ArchivePath)
+		})
+		require.Error(t, err)
+		require.Empty(t, resp.TotalFiles)
+	})
+}
+
+func TestArchiveDirTo(t *testing.T) {
+	t.Run("zip to writer", func(t *testing.T) {
+		dir := createArchiveFixture(t)
+
+		var buf bytes.Buffer
+		resp, err := ArchiveDirTo(t.Context(), dir, &buf, ArchiveOptions{})
+		require.NoError(t, err)
+		require.Empty(t, resp.ArchivePath)
+		require.Equal(t, 2, resp.SuccessFiles)
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		names := make([]string, 0, len(zr.File))
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		require.ElementsMatch(t, []string{"skip.log", "sub/data.txt"}, names)
+	})
+
+	t.Run("tar.zst to writer", func(t *testing.T) {
+		dir := createArchiveFixture(t)
+
+		var buf bytes.Buffer
+		resp, err := ArchiveDirTo(t.Context(), dir, &buf, ArchiveOptions{
+			Format:           ArchiveFormatTarZst,
+			Exclude:          []*regexp.Regexp{regexp.MustCompile(`^sub$`)},
+			CompressionLevel: CompressionBest,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.TotalFiles)
+		require.Equal(t, 1, resp.TotalDirs)
+
+		zr, err := zstd.NewReader(&buf)
+		require.NoError(t, err)
+		defer zr.Close()
+		tr := tar.NewReader(zr)
+		var names []string
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names = append(names, h.Name)
+		}
+		require.ElementsMatch(t, []string{"link", "skip.log"}, names)
+	})
+
+	t.Run("compression level", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), bytes.Repeat([]byte("compress me "), 4096), 0o644))
+
+		sizes := make(map[CompressionLevel]int)
+		for _, level := range []CompressionLevel{CompressionNone, CompressionBest} {
+			var buf bytes.Buffer
+			_, err := ArchiveDirTo(t.Context(), dir, &buf, ArchiveOptions{Format: ArchiveFormatTarGz, CompressionLevel: level})
+			require.NoError(t, err)
+			sizes[level] = buf.Len()
+		}
+		require.Less(t, sizes[CompressionBest], sizes[CompressionNone])
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		dir := createArchiveFixture(t)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		var buf bytes.Buffer
+		resp, err := ArchiveDirTo(ctx, dir, &buf, ArchiveOptions{Format: ArchiveFormatTar})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Zero(t, resp.SuccessFiles)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := ArchiveDirTo(t.Context(), t.TempDir(), io.Discard, ArchiveOptions{Format: "7z"})
+		require.ErrorIs(t, err, ErrUnsupportedArchiveFormat)
+	})
+}
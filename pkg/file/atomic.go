@@ -0,0 +1,205 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultAtomicFileMode is the mode of files created by the atomic writer when no mode is given.
+	DefaultAtomicFileMode os.FileMode = 0o644
+
+	// DefaultBackupSuffix is appended to the path of the previous version kept by the atomic writer.
+	DefaultBackupSuffix = ".bak"
+)
+
+// AtomicWriteOptions configures NewAtomicWriter and WriteFileAtomic.
+type AtomicWriteOptions struct {
+	// Perm, when set, is applied to the file. Otherwise the mode of the replaced file is kept
+	// and new files get DefaultAtomicFileMode.
+	Perm os.FileMode
+	// PreserveOwnership copies the owner and group of the replaced file; this may require privileges.
+	PreserveOwnership bool
+	// Backup keeps the replaced file at path+BackupSuffix.
+	Backup bool
+	// BackupSuffix defaults to DefaultBackupSuffix.
+	BackupSuffix string
+}
+
+// AtomicWriter writes to a temporary file next to the destination and renames it into place on Commit,
+// so readers see either the previous content or the complete new content, never a truncated file.
+// Close without Commit discards the temporary file, which makes `defer w.Close()` safe.
+type AtomicWriter struct {
+	path string
+	tmp  *os.File
+	opts AtomicWriteOptions
+	done bool
+}
+
+// NewAtomicWriter creates an AtomicWriter for path. A symlink at path is followed so its target is replaced.
+func NewAtomicWriter(path string, opts AtomicWriteOptions) (*AtomicWriter, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if opts.BackupSuffix == "" {
+		opts.BackupSuffix = DefaultBackupSuffix
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	return &AtomicWriter{
+		path: path,
+		tmp:  tmp,
+		opts: opts,
+	}, nil
+}
+
+// Write writes p to the temporary file.
+func (w *AtomicWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, os.ErrClosed
+	}
+	return w.tmp.Write(p)
+}
+
+// Name returns the destination path.
+func (w *AtomicWriter) Name() string {
+	return w.path
+}
+
+// Commit flushes the temporary file to disk, applies mode and ownership, optionally backs up
+// the previous version, renames the temporary file over the destination and syncs the directory.
+func (w *AtomicWriter) Commit() error {
+	if w.done {
+		return os.ErrClosed
+	}
+	w.done = true
+
+	if err := w.commit(); err != nil {
+		_ = w.tmp.Close()
+		_ = os.Remove(w.tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (w *AtomicWriter) commit() error {
+	if err := w.tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := w.tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	existing, err := os.Stat(w.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to stat destination: %w", err)
+	}
+	if existing != nil && !existing.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s", ErrUnsupportedFileType, w.path)
+	}
+
+	perm := w.opts.Perm
+	if perm == 0 {
+		perm = DefaultAtomicFileMode
+		if existing != nil {
+			perm = existing.Mode().Perm()
+		}
+	}
+	if err := os.Chmod(w.tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	if existing != nil && w.opts.PreserveOwnership {
+		if err := copyOwnership(existing, w.tmp.Name()); err != nil {
+			return fmt.Errorf("failed to preserve ownership: %w", err)
+		}
+	}
+
+	if existing != nil && w.opts.Backup {
+		if err := backupFile(w.path, w.path+w.opts.BackupSuffix); err != nil {
+			return fmt.Errorf("failed to back up previous version: %w", err)
+		}
+	}
+
+	if err := os.Rename(w.tmp.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(w.path))
+}
+
+// Close discards the temporary file unless Commit was called.
+func (w *AtomicWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+
+	_ = w.tmp.Close()
+	if err := os.Remove(w.tmp.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WriteFileAtomic atomically replaces the content of path with data.
+func WriteFileAtomic(path string, data []byte, opts AtomicWriteOptions) error {
+	w, err := NewAtomicWriter(path, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = w.Close()
+	}()
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	return w.Commit()
+}
+
+// backupFile replaces backupPath with the current version of path, hard linking when possible.
+func backupFile(path, backupPath string) error {
+	if err := os.Remove(backupPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(path, backupPath); err == nil {
+		return nil
+	}
+
+	// Fall back to copying on filesystems without hard links.
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(backupPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dst.Close()
+	}()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// copyOwnership is a no-op on platforms without POSIX ownership.
+func copyOwnership(_ os.FileInfo, _ string) error {
+	return nil
+}
+
+// syncDir is a no-op on platforms that cannot sync directories.
+func syncDir(_ string) error {
+	return nil
+}
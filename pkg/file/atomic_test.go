@@ -0,0 +1,146 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Run("new file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.env")
+
+		err := WriteFileAtomic(path, []byte("A=1\n"), AtomicWriteOptions{})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "A=1\n", string(data))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, DefaultAtomicFileMode, info.Mode().Perm())
+	})
+
+	t.Run("preserves permissions", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+		require.NoError(t, os.Chmod(path, 0o600))
+
+		require.NoError(t, WriteFileAtomic(path, []byte("new"), AtomicWriteOptions{}))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("explicit permissions", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+		require.NoError(t, WriteFileAtomic(path, []byte("new"), AtomicWriteOptions{Perm: 0o600}))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(path, []byte("v1"), 0o644))
+
+		require.NoError(t, WriteFileAtomic(path, []byte("v2"), AtomicWriteOptions{Backup: true}))
+		require.NoError(t, WriteFileAtomic(path, []byte("v3"), AtomicWriteOptions{Backup: true, BackupSuffix: ".prev"}))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "v3", string(data))
+
+		backup, err := os.ReadFile(path + DefaultBackupSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "v1", string(backup))
+
+		prev, err := os.ReadFile(path + ".prev")
+		require.NoError(t, err)
+		require.Equal(t, "v2", string(prev))
+	})
+
+	t.Run("follows symlink", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "real.conf")
+		link := filepath.Join(dir, "link.conf")
+		require.NoError(t, os.WriteFile(target, []byte("old"), 0o644))
+		require.NoError(t, os.Symlink(target, link))
+
+		require.NoError(t, WriteFileAtomic(link, []byte("new"), AtomicWriteOptions{}))
+
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		require.NotZero(t, info.Mode()&os.ModeSymlink)
+
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, "new", string(data))
+	})
+
+	t.Run("destination is a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+
+		err := WriteFileAtomic(filepath.Join(dir, "sub"), []byte("x"), AtomicWriteOptions{})
+		require.ErrorIs(t, err, ErrUnsupportedFileType)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "temp file must be cleaned up")
+	})
+}
+
+func TestAtomicWriter(t *testing.T) {
+	t.Run("close without commit keeps original", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config")
+		require.NoError(t, os.WriteFile(path, []byte("original"), 0o644))
+
+		w, err := NewAtomicWriter(path, AtomicWriteOptions{})
+		require.NoError(t, err)
+		_, err = w.Write([]byte("partial"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "original", string(data))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("write after commit", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config")
+
+		w, err := NewAtomicWriter(path, AtomicWriteOptions{})
+		require.NoError(t, err)
+		require.Equal(t, path, w.Name())
+		_, err = w.Write([]byte("content"))
+		require.NoError(t, err)
+		require.NoError(t, w.Commit())
+		require.NoError(t, w.Close())
+
+		_, err = w.Write([]byte("more"))
+		require.ErrorIs(t, err, os.ErrClosed)
+		require.ErrorIs(t, w.Commit(), os.ErrClosed)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "content", string(data))
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := NewAtomicWriter(filepath.Join(t.TempDir(), "missing", "config"), AtomicWriteOptions{})
+		require.Error(t, err)
+	})
+}
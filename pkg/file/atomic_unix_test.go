@@ -0,0 +1,31 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomicPreserveOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+
+	path := filepath.Join(t.TempDir(), "owned")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+	require.NoError(t, os.Chown(path, 1234, 5678))
+
+	require.NoError(t, WriteFileAtomic(path, []byte("new"), AtomicWriteOptions{PreserveOwnership: true}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	require.True(t, ok)
+	require.Equal(t, uint32(1234), stat.Uid)
+	require.Equal(t, uint32(5678), stat.Gid)
+}
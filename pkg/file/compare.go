@@ -0,0 +1,191 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hibare/GoCommon/v2/pkg/crypto/hash"
+)
+
+// CompareOptions configures CompareDirs.
+type CompareOptions struct {
+	// Walk filters the files compared on both sides. Symlinks are skipped unless they are followed.
+	Walk WalkOptions
+	// AlwaysHash compares the content of files even when size and modification time are equal,
+	// e.g. to verify a restored backup.
+	AlwaysHash bool
+}
+
+// DirDiff lists the slash separated paths, relative to the compared directories, that differ.
+type DirDiff struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Equal reports whether no difference was found.
+func (d DirDiff) Equal() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// CompareDirs compares the files of oldDir and newDir. Files of different size are modified, files of equal size and
+// modification time are unchanged, and the remaining ones are compared by SHA-256 hash.
+func CompareDirs(ctx context.Context, oldDir, newDir string, opts CompareOptions) (DirDiff, error) {
+	var diff DirDiff
+
+	oldFiles, err := listRegularFiles(ctx, oldDir, opts.Walk)
+	if err != nil {
+		return diff, err
+	}
+	newFiles, err := listRegularFiles(ctx, newDir, opts.Walk)
+	if err != nil {
+		return diff, err
+	}
+
+	hasher := hash.NewSHA256Hasher()
+	for relPath, newInfo := range newFiles {
+		if err := ctx.Err(); err != nil {
+			return diff, err
+		}
+
+		oldInfo, ok := oldFiles[relPath]
+		if !ok {
+			diff.Added = append(diff.Added, relPath)
+			continue
+		}
+		if oldInfo.Size() != newInfo.Size() {
+			diff.Modified = append(diff.Modified, relPath)
+			continue
+		}
+		if !opts.AlwaysHash && oldInfo.ModTime().Equal(newInfo.ModTime()) {
+			continue
+		}
+
+		oldHash, err := hasher.HashFile(filepath.Join(oldDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return diff, fmt.Errorf("failed to hash %s: %w", relPath, err)
+		}
+		newHash, err := hasher.HashFile(filepath.Join(newDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return diff, fmt.Errorf("failed to hash %s: %w", relPath, err)
+		}
+		if oldHash != newHash {
+			diff.Modified = append(diff.Modified, relPath)
+		}
+	}
+	for relPath := range oldFiles {
+		if _, ok := newFiles[relPath]; !ok {
+			diff.Removed = append(diff.Removed, relPath)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+
+	return diff, nil
+}
+
+// DuplicateOptions configures FindDuplicates.
+type DuplicateOptions struct {
+	// Walk filters the files considered. Symlinks are skipped unless they are followed.
+	Walk WalkOptions
+	// IncludeEmpty also groups empty files, which are otherwise ignored.
+	IncludeEmpty bool
+}
+
+// DuplicateGroup is a set of files with identical content.
+type DuplicateGroup struct {
+	// Hash is the hex encoded SHA-256 of the content.
+	Hash string
+	Size int64
+	// Paths are the slash separated paths relative to the searched directory, sorted.
+	Paths []string
+}
+
+// Wasted returns the bytes that removing all but one copy would free.
+func (g DuplicateGroup) Wasted() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// FindDuplicates groups the files below root with identical content. Only files sharing their size
+// with another file are hashed. Groups are sorted by wasted bytes, largest first.
+func FindDuplicates(ctx context.Context, root string, opts DuplicateOptions) ([]DuplicateGroup, error) {
+	files, err := listRegularFiles(ctx, root, opts.Walk)
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]string)
+	for relPath, info := range files {
+		if info.Size() == 0 && !opts.IncludeEmpty {
+			continue
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], relPath)
+	}
+
+	hasher := hash.NewSHA256Hasher()
+	var groups []DuplicateGroup
+	for size, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
+		}
+
+		byHash := make(map[string][]string)
+		for _, relPath := range candidates {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			sum, err := hasher.HashFile(filepath.Join(root, filepath.FromSlash(relPath)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", relPath, err)
+			}
+			byHash[sum] = append(byHash[sum], relPath)
+		}
+
+		for sum, paths := range byHash {
+			if len(paths) < 2 {
+				continue
+			}
+			sort.Strings(paths)
+			groups = append(groups, DuplicateGroup{Hash: sum, Size: size, Paths: paths})
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Wasted() != groups[j].Wasted() {
+			return groups[i].Wasted() > groups[j].Wasted()
+		}
+		return groups[i].Paths[0] < groups[j].Paths[0]
+	})
+
+	return groups, nil
+}
+
+// listRegularFiles walks dir and returns its regular files by relative path.
+func listRegularFiles(ctx context.Context, dir string, opts WalkOptions) (map[string]fs.FileInfo, error) {
+	if opts.Symlinks != SymlinkFollow {
+		opts.Symlinks = SymlinkIgnore
+	}
+	opts.IncludeDirs = false
+
+	files := make(map[string]fs.FileInfo)
+	for entry, err := range Walk(os.DirFS(dir), ".", opts) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if entry.Info.Mode().IsRegular() {
+			files[entry.RelPath] = entry.Info
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,129 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTree creates the given files below dir with a fixed modification time.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+}
+
+func TestCompareDirs(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	writeTree(t, oldDir, map[string]string{
+		"same.txt":       "same",
+		"sub/resized":    "short",
+		"sub/touched":    "abcd",
+		"sub/rewritten":  "abcd",
+		"removed.txt":    "gone",
+		"skipped/a.tmp":  "tmp",
+		"corrupted.data": "good",
+	})
+	writeTree(t, newDir, map[string]string{
+		"same.txt":       "same",
+		"sub/resized":    "much longer",
+		"sub/touched":    "abcd",
+		"sub/rewritten":  "wxyz",
+		"added.txt":      "new",
+		"corrupted.data": "evil",
+	})
+
+	later := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(newDir, "sub", "touched"), later, later))
+	require.NoError(t, os.Chtimes(filepath.Join(newDir, "sub", "rewritten"), later, later))
+
+	t.Run("size, mtime and hash", func(t *testing.T) {
+		diff, err := CompareDirs(t.Context(), oldDir, newDir, CompareOptions{Walk: WalkOptions{Exclude: []string{"*.tmp"}}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"added.txt"}, diff.Added)
+		require.Equal(t, []string{"removed.txt"}, diff.Removed)
+		// corrupted.data has the same size and mtime, so only AlwaysHash catches it
+		require.Equal(t, []string{"sub/resized", "sub/rewritten"}, diff.Modified)
+		require.False(t, diff.Equal())
+	})
+
+	t.Run("always hash", func(t *testing.T) {
+		diff, err := CompareDirs(t.Context(), oldDir, newDir, CompareOptions{AlwaysHash: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"corrupted.data", "sub/resized", "sub/rewritten"}, diff.Modified)
+		require.Equal(t, []string{"removed.txt", "skipped/a.tmp"}, diff.Removed)
+	})
+
+	t.Run("identical", func(t *testing.T) {
+		diff, err := CompareDirs(t.Context(), oldDir, oldDir, CompareOptions{AlwaysHash: true})
+		require.NoError(t, err)
+		require.True(t, diff.Equal())
+	})
+
+	t.Run("missing dir", func(t *testing.T) {
+		_, err := CompareDirs(t.Context(), oldDir, filepath.Join(newDir, "missing"), CompareOptions{})
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		_, err := CompareDirs(ctx, oldDir, newDir, CompareOptions{})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a/photo.jpg":      "jpeg-bytes",
+		"b/photo-copy.jpg": "jpeg-bytes",
+		"c/photo.jpg":      "jpeg-bytes",
+		"same-size.txt":    "other-byte",
+		"doc.txt":          "doc",
+		"backup/doc.txt":   "doc",
+		"empty1":           "",
+		"empty2":           "",
+	})
+	require.NoError(t, os.Symlink("doc.txt", filepath.Join(dir, "doc-link")))
+
+	t.Run("groups", func(t *testing.T) {
+		groups, err := FindDuplicates(t.Context(), dir, DuplicateOptions{})
+		require.NoError(t, err)
+		require.Len(t, groups, 2)
+
+		require.Equal(t, []string{"a/photo.jpg", "b/photo-copy.jpg", "c/photo.jpg"}, groups[0].Paths)
+		require.Equal(t, int64(len("jpeg-bytes")), groups[0].Size)
+		require.Equal(t, int64(2*len("jpeg-bytes")), groups[0].Wasted())
+		require.Len(t, groups[0].Hash, 64)
+
+		require.Equal(t, []string{"backup/doc.txt", "doc.txt"}, groups[1].Paths)
+	})
+
+	t.Run("include empty", func(t *testing.T) {
+		groups, err := FindDuplicates(t.Context(), dir, DuplicateOptions{IncludeEmpty: true, Walk: WalkOptions{Include: []string{"empty*"}}})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		require.Equal(t, []string{"empty1", "empty2"}, groups[0].Paths)
+		require.Zero(t, groups[0].Wasted())
+	})
+
+	t.Run("follow symlinks", func(t *testing.T) {
+		groups, err := FindDuplicates(t.Context(), dir, DuplicateOptions{Walk: WalkOptions{Symlinks: SymlinkFollow, Include: []string{"doc*"}}})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		require.Equal(t, []string{"backup/doc.txt", "doc-link", "doc.txt"}, groups[0].Paths)
+	})
+}
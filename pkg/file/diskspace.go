@@ -0,0 +1,33 @@
+package file
+
+import "fmt"
+
+// DiskSpace describes the capacity of the filesystem holding a path, in bytes.
+type DiskSpace struct {
+	Total uint64
+	Free  uint64
+	// Available is the free space usable by unprivileged processes, which excludes reserved blocks.
+	Available uint64
+}
+
+// GetDiskSpace returns the capacity of the filesystem holding path.
+func GetDiskSpace(path string) (DiskSpace, error) {
+	space, err := diskSpace(path)
+	if err != nil {
+		return DiskSpace{}, fmt.Errorf("failed to get disk space of %s: %w", path, err)
+	}
+	return space, nil
+}
+
+// EnsureFreeSpace returns ErrInsufficientSpace unless the filesystem holding path has at least required bytes
+// available, e.g. before ArchiveDir writes to os.TempDir() or DownloadFile writes to its destination directory.
+func EnsureFreeSpace(path string, required uint64) error {
+	space, err := GetDiskSpace(path)
+	if err != nil {
+		return err
+	}
+	if space.Available < required {
+		return fmt.Errorf("%w: %s has %d bytes available, %d required", ErrInsufficientSpace, path, space.Available, required)
+	}
+	return nil
+}
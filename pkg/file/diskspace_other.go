@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || dragonfly || windows)
+
+package file
+
+import "errors"
+
+// diskSpace is not implemented on platforms without a compatible statfs.
+func diskSpace(_ string) (DiskSpace, error) {
+	return DiskSpace{}, errors.ErrUnsupported
+}
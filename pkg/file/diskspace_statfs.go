@@ -0,0 +1,20 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package file
+
+import "golang.org/x/sys/unix"
+
+//nolint:unconvert,gosec // reason: Statfs_t field types differ across platforms and are never negative
+func diskSpace(path string) (DiskSpace, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return DiskSpace{}, err
+	}
+
+	blockSize := uint64(stat.Bsize)
+	return DiskSpace{
+		Total:     uint64(stat.Blocks) * blockSize,
+		Free:      uint64(stat.Bfree) * blockSize,
+		Available: uint64(stat.Bavail) * blockSize,
+	}, nil
+}
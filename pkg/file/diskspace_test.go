@@ -0,0 +1,25 @@
+package file
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDiskSpace(t *testing.T) {
+	space, err := GetDiskSpace(t.TempDir())
+	require.NoError(t, err)
+	require.NotZero(t, space.Total)
+	require.LessOrEqual(t, space.Available, space.Free)
+	require.LessOrEqual(t, space.Free, space.Total)
+
+	_, err = GetDiskSpace("/does/not/exist")
+	require.Error(t, err)
+}
+
+func TestEnsureFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, EnsureFreeSpace(dir, 1))
+	require.ErrorIs(t, EnsureFreeSpace(dir, math.MaxUint64), ErrInsufficientSpace)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package file
+
+import "golang.org/x/sys/windows"
+
+func diskSpace(path string) (DiskSpace, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskSpace{}, err
+	}
+
+	var space DiskSpace
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &space.Available, &space.Total, &space.Free); err != nil {
+		return DiskSpace{}, err
+	}
+	return space, nil
+}
@@ -0,0 +1,282 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/GoCommon/v2/pkg/crypto/hash"
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/hibare/GoCommon/v2/pkg/http/client"
+)
+
+const (
+	// DownloadPartSuffix is appended to the destination path while a download is in progress.
+	DownloadPartSuffix = ".part"
+
+	// DownloadLockSuffix is appended to the destination path for the lock held while a download is in progress.
+	DownloadLockSuffix = ".part.lock"
+
+	// DefaultDownloadRetryBackoff is the initial wait between download attempts; it doubles after each retry.
+	DefaultDownloadRetryBackoff = time.Second
+)
+
+// DownloadProgressFunc is called as bytes are written. total is -1 when the size is unknown.
+type DownloadProgressFunc func(written, total int64)
+
+// DownloadOptions configures DownloadFileWithOptions.
+type DownloadOptions struct {
+	// Client performs the requests; defaults to http.DefaultClient.
+	Client client.ClientIface
+	// ExpectedSHA256 is the hex encoded checksum the download must match.
+	ExpectedSHA256 string
+	// Resume continues from a partial download left behind by a previous call and keeps it on failure.
+	Resume bool
+	// MaxRetries is the number of additional attempts after a network error, 408, 429 or 5xx response.
+	MaxRetries int
+	// RetryBackoff is the initial wait between attempts; defaults to DefaultDownloadRetryBackoff.
+	RetryBackoff time.Duration
+	// Progress reports the bytes written so far.
+	Progress DownloadProgressFunc
+}
+
+// DownloadFile downloads a file from the given URL to the specified destination path.
+func DownloadFile(ctx context.Context, url string, destination string) error {
+	return DownloadFileWithOptions(ctx, url, destination, DownloadOptions{})
+}
+
+// DownloadFileWithOptions downloads a file from the given URL to the specified destination path.
+// The content is written to destination+DownloadPartSuffix and only renamed into place once complete and verified,
+// so destination is never left partially written. Retries resume from the bytes already received using Range requests.
+// The partial download is locked with TryLock, so a concurrent download to the same destination fails with ErrLocked.
+func DownloadFileWithOptions(ctx context.Context, url string, destination string, opts DownloadOptions) error {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultDownloadRetryBackoff
+	}
+
+	lock, err := TryLock(destination + DownloadLockSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to lock partial download: %w", err)
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	partPath := destination + DownloadPartSuffix
+	if !opts.Resume {
+		if err := os.Remove(partPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove partial download: %w", err)
+		}
+	}
+
+	err = downloadWithRetries(ctx, url, partPath, opts)
+	if err != nil {
+		if !opts.Resume {
+			_ = os.Remove(partPath)
+		}
+		return err
+	}
+
+	if opts.ExpectedSHA256 != "" {
+		ok, err := hash.NewSHA256Hasher().VerifyFile(partPath, strings.ToLower(opts.ExpectedSHA256))
+		if err != nil {
+			return fmt.Errorf("failed to hash download: %w", err)
+		}
+		if !ok {
+			_ = os.Remove(partPath)
+			return commonErrors.ErrChecksumMismatch
+		}
+	}
+
+	if err := os.Rename(partPath, destination); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+
+	return nil
+}
+
+// retryableError marks failures worth another attempt.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string { return e.err.Error() }
+
+func (e retryableError) Unwrap() error { return e.err }
+
+func downloadWithRetries(ctx context.Context, url, partPath string, opts DownloadOptions) error {
+	backoff := opts.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := downloadAttempt(ctx, url, partPath, opts)
+		if err == nil {
+			return nil
+		}
+
+		var retryable retryableError
+		if !errors.As(err, &retryable) || attempt >= opts.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// downloadAttempt performs a single request, appending to partPath when the server honours the Range request.
+func downloadAttempt(ctx context.Context, url, partPath string, opts DownloadOptions) error {
+	out, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek destination file: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := opts.Client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to get url: %w", err)
+		}
+		return retryableError{fmt.Errorf("failed to get url: %w", err)}
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	var total int64
+	rangeStart, rangeTotal := parseContentRange(response)
+	switch {
+	case response.StatusCode == http.StatusPartialContent && offset > 0 && rangeStart == offset:
+		total = rangeTotal
+	case response.StatusCode == http.StatusOK:
+		// The server ignored the Range request, start over.
+		if err := out.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate destination file: %w", err)
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek destination file: %w", err)
+		}
+		offset = 0
+		total = response.ContentLength
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && rangeStart == -1 && rangeTotal == offset:
+		// The partial file already holds the whole remote content.
+		if opts.Progress != nil {
+			opts.Progress(offset, offset)
+		}
+		return nil
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable, response.StatusCode == http.StatusPartialContent:
+		// The partial file does not line up with the remote content, discard it and retry from scratch.
+		if err := out.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate destination file: %w", err)
+		}
+		return retryableError{fmt.Errorf("%w: bad status: %s", commonErrors.ErrNonOKError, response.Status)}
+	default:
+		err := fmt.Errorf("%w: bad status: %s", commonErrors.ErrNonOKError, response.Status)
+		if isRetryableStatus(response.StatusCode) {
+			return retryableError{err}
+		}
+		return err
+	}
+
+	var w io.Writer = out
+	if opts.Progress != nil {
+		w = &progressWriter{w: out, written: offset, total: total, progress: opts.Progress}
+	}
+
+	if _, err := io.Copy(w, response.Body); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to copy response body: %w", err)
+		}
+		return retryableError{fmt.Errorf("failed to copy response body: %w", err)}
+	}
+
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("failed to sync destination file: %w", err)
+	}
+
+	return nil
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// parseContentRange returns the start and total of a "bytes start-end/total" Content-Range header.
+// The start is -1 for the "bytes */total" form sent with 416 responses.
+func parseContentRange(response *http.Response) (int64, int64) {
+	value, ok := strings.CutPrefix(response.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1, -1
+	}
+
+	span, totalStr, ok := strings.Cut(value, "/")
+	if !ok {
+		return -1, -1
+	}
+	if span == "*" {
+		total, err := strconv.ParseInt(totalStr, 10, 64)
+		if err != nil {
+			return -1, -1
+		}
+		return -1, total
+	}
+	startStr, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return -1, -1
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return -1, -1
+	}
+	total, err := strconv.ParseInt(totalStr, 10, 64)
+	if err != nil {
+		// "*" means the total size is unknown
+		total = -1
+	}
+
+	return start, total
+}
+
+// progressWriter reports the cumulative bytes written to a DownloadProgressFunc.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress DownloadProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
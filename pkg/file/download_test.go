@@ -0,0 +1,258 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/hibare/GoCommon/v2/pkg/http/client"
+	"github.com/hibare/GoCommon/v2/pkg/testhelper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadFile(t *testing.T) {
+	t.Run("Valid Download", func(t *testing.T) {
+		// Create a test file
+		_, absPath, err := testhelper.CreateTestFile(t.TempDir(), "")
+		require.NoError(t, err)
+
+		// Create a mock HTTP server
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Serve a test file
+			http.ServeFile(w, r, absPath)
+		}))
+		defer server.Close()
+
+		downloadFilePath := filepath.Join(t.TempDir(), "test-file.txt")
+		t.Cleanup(func() {
+			_ = os.Remove(downloadFilePath)
+		})
+
+		// Download the file using the download function
+		err = DownloadFile(t.Context(), server.URL, downloadFilePath)
+		require.NoError(t, err)
+
+		lines, err := ReadFileLines(downloadFilePath)
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+	})
+
+	t.Run("Invalid Download", func(t *testing.T) {
+		// Create a mock HTTP server
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Serve a test file
+			http.ServeFile(w, r, "some/random/path")
+		}))
+		defer server.Close()
+
+		downloadFilePath := filepath.Join(t.TempDir(), "test-file.txt")
+		t.Cleanup(func() {
+			_ = os.Remove(downloadFilePath)
+		})
+
+		// Download the file using the download function
+		err := DownloadFile(t.Context(), server.URL, downloadFilePath)
+		require.Error(t, err)
+
+		lines, err := ReadFileLines(downloadFilePath)
+		require.Error(t, err)
+		require.Nil(t, lines)
+	})
+}
+
+func TestDownloadFileWithOptions(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	serveContent := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}
+
+	t.Run("checksum verified", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(serveContent))
+		defer server.Close()
+
+		var lastWritten, lastTotal int64
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{
+			ExpectedSHA256: strings.ToUpper(checksum),
+			Progress: func(written, total int64) {
+				lastWritten, lastTotal = written, total
+			},
+		})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+		require.NoFileExists(t, dest+DownloadPartSuffix)
+		require.Equal(t, int64(len(content)), lastWritten)
+		require.Equal(t, int64(len(content)), lastTotal)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(serveContent))
+		defer server.Close()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{ExpectedSHA256: strings.Repeat("0", 64)})
+		require.ErrorIs(t, err, commonErrors.ErrChecksumMismatch)
+		require.NoFileExists(t, dest)
+		require.NoFileExists(t, dest+DownloadPartSuffix)
+	})
+
+	t.Run("retry resumes interrupted transfer", func(t *testing.T) {
+		var requests atomic.Int32
+		var rangeHeader atomic.Value
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				// Promise the full body but drop the connection halfway through.
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				_, _ = w.Write(content[:len(content)/2])
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			rangeHeader.Store(r.Header.Get("Range"))
+			serveContent(w, r)
+		}))
+		defer server.Close()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{
+			ExpectedSHA256: checksum,
+			MaxRetries:     2,
+			RetryBackoff:   time.Millisecond,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), requests.Load())
+		require.Equal(t, "bytes="+strconv.Itoa(len(content)/2)+"-", rangeHeader.Load())
+
+		data, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+	})
+
+	t.Run("resume partial download", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(serveContent))
+		defer server.Close()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		require.NoError(t, os.WriteFile(dest+DownloadPartSuffix, content[:100], 0o644))
+
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{Resume: true, ExpectedSHA256: checksum})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+	})
+
+	t.Run("resume complete partial download", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(serveContent))
+		defer server.Close()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		require.NoError(t, os.WriteFile(dest+DownloadPartSuffix, content, 0o644))
+
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{Resume: true, ExpectedSHA256: checksum})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+		require.NoFileExists(t, dest+DownloadPartSuffix)
+	})
+
+	t.Run("concurrent download to the same destination", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		lock, err := TryLock(dest + DownloadLockSuffix)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(dest+DownloadPartSuffix, content[:100], 0o644))
+
+		err = DownloadFileWithOptions(t.Context(), "http://example.invalid/data.bin", dest, DownloadOptions{})
+		require.ErrorIs(t, err, ErrLocked)
+		require.FileExists(t, dest+DownloadPartSuffix)
+
+		require.NoError(t, lock.Unlock())
+	})
+
+	t.Run("server ignores range", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(content)
+		}))
+		defer server.Close()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		require.NoError(t, os.WriteFile(dest+DownloadPartSuffix, []byte("stale"), 0o644))
+
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{Resume: true, ExpectedSHA256: checksum})
+		require.NoError(t, err)
+	})
+
+	t.Run("client error is not retried", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		err := DownloadFileWithOptions(t.Context(), server.URL, dest, DownloadOptions{MaxRetries: 3, RetryBackoff: time.Millisecond})
+		require.ErrorIs(t, err, commonErrors.ErrNonOKError)
+		require.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("injected client", func(t *testing.T) {
+		mockClient := new(client.MockClient)
+		mockClient.On("Do", mock.Anything).Return(nil, errors.New("connection reset")).Once()
+		mockClient.On("Do", mock.Anything).Return(&http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(bytes.NewReader(content)),
+			ContentLength: int64(len(content)),
+		}, nil).Once()
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		err := DownloadFileWithOptions(t.Context(), "http://example.invalid/data.bin", dest, DownloadOptions{
+			Client:         mockClient,
+			ExpectedSHA256: checksum,
+			MaxRetries:     1,
+			RetryBackoff:   time.Millisecond,
+		})
+		require.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("retries exhausted keeps partial file when resuming", func(t *testing.T) {
+		mockClient := new(client.MockClient)
+		mockClient.On("Do", mock.Anything).Return(nil, errors.New("connection reset"))
+
+		dest := filepath.Join(t.TempDir(), "data.bin")
+		require.NoError(t, os.WriteFile(dest+DownloadPartSuffix, content[:10], 0o644))
+
+		err := DownloadFileWithOptions(t.Context(), "http://example.invalid/data.bin", dest, DownloadOptions{
+			Client:       mockClient,
+			Resume:       true,
+			MaxRetries:   1,
+			RetryBackoff: time.Millisecond,
+		})
+		require.Error(t, err)
+		require.FileExists(t, dest+DownloadPartSuffix)
+		require.NoFileExists(t, dest)
+		mockClient.AssertNumberOfCalls(t, "Do", 2)
+	})
+}
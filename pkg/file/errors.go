@@ -0,0 +1,26 @@
+package file
+
+import "errors"
+
+var (
+	// ErrUnsupportedArchiveFormat indicates an archive format that is not supported.
+	ErrUnsupportedArchiveFormat = errors.New("unsupported archive format")
+
+	// ErrUnsupportedFileType indicates a file type that cannot be archived.
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+
+	// ErrPathTraversal indicates an archive entry that would be written outside the destination.
+	ErrPathTraversal = errors.New("archive entry escapes destination")
+
+	// ErrSymlinkEscape indicates an archive link whose target is outside the destination.
+	ErrSymlinkEscape = errors.New("archive link escapes destination")
+
+	// ErrArchiveTooLarge indicates an archive exceeding the configured size or entry limits.
+	ErrArchiveTooLarge = errors.New("archive exceeds extraction limits")
+
+	// ErrInsufficientSpace indicates a filesystem without enough free space for an operation.
+	ErrInsufficientSpace = errors.New("insufficient disk space")
+
+	// ErrLocked indicates a lock held by another process.
+	ErrLocked = errors.New("lock is held by another process")
+)
@@ -0,0 +1,560 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// DefaultExtractMaxTotalSize is the default limit on the uncompressed size of an extracted archive.
+	DefaultExtractMaxTotalSize int64 = 10 << 30 // 10 GiB
+
+	// DefaultExtractMaxFiles is the default limit on the number of entries in an extracted archive.
+	DefaultExtractMaxFiles = 100_000
+
+	defaultExtractFileMode os.FileMode = 0o644
+	defaultExtractDirMode  os.FileMode = 0o755
+)
+
+// ExtractOptions configures ExtractArchive and ExtractArchiveFrom.
+type ExtractOptions struct {
+	// Format is the archive format; ExtractArchive detects it from the file extension when empty.
+	Format ArchiveFormat
+	// MaxTotalSize limits the total uncompressed bytes written; defaults to DefaultExtractMaxTotalSize.
+	MaxTotalSize int64
+	// MaxFiles limits the number of entries extracted; defaults to DefaultExtractMaxFiles.
+	MaxFiles int
+	// PreservePermissions restores the permission bits stored in the archive.
+	PreservePermissions bool
+	// PreserveModTime restores the modification times stored in the archive.
+	PreserveModTime bool
+}
+
+func (o ExtractOptions) withDefaults() ExtractOptions {
+	if o.MaxTotalSize <= 0 {
+		o.MaxTotalSize = DefaultExtractMaxTotalSize
+	}
+	if o.MaxFiles <= 0 {
+		o.MaxFiles = DefaultExtractMaxFiles
+	}
+	return o
+}
+
+// ExtractResponse represents the result of extracting an archive.
+type ExtractResponse struct {
+	TotalFiles int
+	TotalDirs  int
+	TotalSize  int64
+}
+
+// DetectArchiveFormat returns the archive format matching the file extension of path.
+func DetectArchiveFormat(path string) (ArchiveFormat, error) {
+	name := strings.ToLower(filepath.Base(path))
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveFormatTarGz, nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return ArchiveFormatTarZst, nil
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveFormatTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveFormatZip, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedArchiveFormat, filepath.Ext(name))
+	}
+}
+
+// ExtractArchive extracts a zip or tar based archive into destDir, guarding against path traversal,
+// symlink escapes and decompression bombs. Extraction stops at the first error.
+func ExtractArchive(ctx context.Context, archivePath, destDir string, opts ExtractOptions) (ExtractResponse, error) {
+	if opts.Format == "" {
+		format, err := DetectArchiveFormat(archivePath)
+		if err != nil {
+			return ExtractResponse{}, err
+		}
+		opts.Format = format
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return ExtractResponse{}, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if opts.Format != ArchiveFormatZip {
+		return ExtractArchiveFrom(ctx, file, destDir, opts)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return ExtractResponse{}, fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	e, err := newExtractor(ctx, destDir, opts)
+	if err != nil {
+		return ExtractResponse{}, err
+	}
+
+	err = e.extractZip(file, info.Size())
+	return e.resp, err
+}
+
+// ExtractArchiveFrom extracts a tar based archive streamed from r into destDir with the same protections as ExtractArchive.
+// Zip archives need random access and must be extracted with ExtractArchive.
+func ExtractArchiveFrom(ctx context.Context, r io.Reader, destDir string, opts ExtractOptions) (ExtractResponse, error) {
+	var reader io.Reader
+
+	switch opts.Format {
+	case ArchiveFormatTar:
+		reader = r
+	case ArchiveFormatTarGz:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return ExtractResponse{}, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer func() {
+			_ = gr.Close()
+		}()
+		reader = gr
+	case ArchiveFormatTarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return ExtractResponse{}, fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		defer zr.Close()
+		reader = zr
+	default:
+		return ExtractResponse{}, fmt.Errorf("%w: %s", ErrUnsupportedArchiveFormat, opts.Format)
+	}
+
+	e, err := newExtractor(ctx, destDir, opts)
+	if err != nil {
+		return ExtractResponse{}, err
+	}
+
+	err = e.extractTar(tar.NewReader(reader))
+	return e.resp, err
+}
+
+// dirAttrs records the mode and mtime of a directory to apply once all its children are written.
+type dirAttrs struct {
+	path    string
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// extractor holds the state shared by the zip and tar extraction loops.
+type extractor struct {
+	ctx      context.Context
+	destDir  string
+	opts     ExtractOptions
+	resp     ExtractResponse
+	dirs     []dirAttrs
+}
+
+func newExtractor(ctx context.Context, destDir string, opts ExtractOptions) (*extractor, error) {
+	if err := os.MkdirAll(destDir, defaultExtractDirMode); err != nil {
+		return nil, fmt.Errorf("%w: %w", commonErrors.ErrCreatingDir, err)
+	}
+
+	// Resolve the destination so symlinked parents (e.g. /tmp on macOS) do not trip the escape checks.
+	resolved, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve destination: %w", err)
+	}
+
+	return &extractor{
+		ctx:     ctx,
+		destDir: resolved,
+		opts:    opts.withDefaults(),
+	}, nil
+}
+
+func (e *extractor) extractTar(tr *tar.Reader) error {
+	for {
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			return e.finish()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.dir(header.Name, mode, header.ModTime)
+		case tar.TypeReg:
+			err = e.file(header.Name, tr, header.Size, mode, header.ModTime)
+		case tar.TypeSymlink:
+			err = e.symlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = e.hardlink(header.Name, header.Linkname)
+		default:
+			err = fmt.Errorf("%w: %s has tar type %q", ErrUnsupportedFileType, header.Name, header.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *extractor) extractZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to create zip reader: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = e.dir(f.Name, mode, f.Modified)
+		case mode&os.ModeSymlink != 0:
+			err = e.zipSymlink(f)
+		case mode.IsRegular():
+			err = e.zipFile(f)
+		default:
+			err = fmt.Errorf("%w: %s has mode %s", ErrUnsupportedFileType, f.Name, mode.Type())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.finish()
+}
+
+func (e *extractor) zipFile(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in zip: %w", f.Name, err)
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	// The declared size is untrusted; file enforces the limits on the bytes actually read.
+	return e.file(f.Name, rc, int64(f.UncompressedSize64), f.Mode(), f.Modified) //nolint:gosec // reason: size is only used as a limit hint
+}
+
+func (e *extractor) zipSymlink(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in zip: %w", f.Name, err)
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read symlink %s: %w", f.Name, err)
+	}
+
+	return e.symlink(f.Name, string(target))
+}
+
+// target validates an archive entry name and returns its path inside destDir.
+// It rejects absolute and parent-relative names and parents that resolve outside destDir through symlinks.
+func (e *extractor) target(name string) (string, error) {
+	name = strings.TrimSuffix(filepath.FromSlash(name), string(filepath.Separator))
+	if name == "" || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: %s", ErrPathTraversal, name)
+	}
+
+	path := filepath.Join(e.destDir, name)
+	if err := e.mkdirAll(filepath.Dir(name)); err != nil {
+		return "", err
+	}
+
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if !e.within(parent) {
+		return "", fmt.Errorf("%w: %s", ErrPathTraversal, name)
+	}
+
+	return path, nil
+}
+
+// mkdirAll creates the directories of rel below destDir one component at a time, so a symlink met on the way
+// is checked before anything is created through it.
+func (e *extractor) mkdirAll(rel string) error {
+	path := e.destDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "" || part == "." {
+			continue
+		}
+		path = filepath.Join(path, part)
+
+		info, err := os.Lstat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if err := os.Mkdir(path, defaultExtractDirMode); err != nil {
+				return fmt.Errorf("%w: %w", commonErrors.ErrCreatingDir, err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		case info.Mode()&os.ModeSymlink != 0:
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", path, err)
+			}
+			if !e.within(resolved) {
+				return fmt.Errorf("%w: %s", ErrPathTraversal, rel)
+			}
+			path = resolved
+		case !info.IsDir():
+			return fmt.Errorf("%w: %s", commonErrors.ErrNotDir, path)
+		}
+	}
+	return nil
+}
+
+// within reports whether path is destDir or inside it.
+func (e *extractor) within(path string) bool {
+	rel, err := filepath.Rel(e.destDir, path)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
+
+// countEntry enforces the MaxFiles limit.
+func (e *extractor) countEntry() error {
+	if e.resp.TotalFiles+e.resp.TotalDirs >= e.opts.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, e.opts.MaxFiles)
+	}
+	return nil
+}
+
+// removeExisting removes a non-directory entry so it is replaced rather than written through.
+func removeExisting(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%w: %s", commonErrors.ErrNotFile, path)
+	}
+	return os.Remove(path)
+}
+
+func (e *extractor) dir(name string, mode os.FileMode, modTime time.Time) error {
+	if err := e.countEntry(); err != nil {
+		return err
+	}
+
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() {
+		return fmt.Errorf("%w: %s", commonErrors.ErrNotDir, name)
+	}
+	if err := os.MkdirAll(path, defaultExtractDirMode); err != nil {
+		return fmt.Errorf("%w: %w", commonErrors.ErrCreatingDir, err)
+	}
+
+	// A read-only directory would block its own children, so modes are applied with mtimes in finish.
+	if e.opts.PreservePermissions || e.opts.PreserveModTime {
+		e.dirs = append(e.dirs, dirAttrs{path: path, mode: mode.Perm(), modTime: modTime})
+	}
+
+	e.resp.TotalDirs++
+	return nil
+}
+
+func (e *extractor) file(name string, r io.Reader, size int64, mode os.FileMode, modTime time.Time) error {
+	if err := e.countEntry(); err != nil {
+		return err
+	}
+
+	remaining := e.opts.MaxTotalSize - e.resp.TotalSize
+	if size > remaining {
+		return fmt.Errorf("%w: exceeds %d bytes", ErrArchiveTooLarge, e.opts.MaxTotalSize)
+	}
+
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := removeExisting(path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+
+	perm := defaultExtractFileMode
+	if e.opts.PreservePermissions {
+		perm = mode.Perm()
+	}
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	// Read one byte past the limit to detect entries that lie about their size.
+	written, err := io.Copy(out, io.LimitReader(contextReader{ctx: e.ctx, r: r}, remaining+1))
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if written > remaining {
+		return fmt.Errorf("%w: exceeds %d bytes", ErrArchiveTooLarge, e.opts.MaxTotalSize)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", name, err)
+	}
+	if e.opts.PreservePermissions {
+		// Apply explicitly as OpenFile permissions are subject to the umask.
+		if err := os.Chmod(path, perm); err != nil {
+			return fmt.Errorf("failed to set permissions on %s: %w", name, err)
+		}
+	}
+	if e.opts.PreserveModTime {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			return fmt.Errorf("failed to set modification time on %s: %w", name, err)
+		}
+	}
+
+	e.resp.TotalSize += written
+	e.resp.TotalFiles++
+	return nil
+}
+
+func (e *extractor) symlink(name, linkname string) error {
+	if err := e.countEntry(); err != nil {
+		return err
+	}
+
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+
+	// ".." is applied to the real parent, which target verified to be inside destDir.
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if !verifiableLinkTarget(linkname) || !e.within(filepath.Join(parent, linkname)) {
+		return fmt.Errorf("%w: %s -> %s", ErrSymlinkEscape, name, linkname)
+	}
+
+	if err := removeExisting(path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+	if err := os.Symlink(linkname, path); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", name, err)
+	}
+
+	// Also verify the resolved target in case destDir already held symlinks.
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && !e.within(resolved) {
+		_ = os.Remove(path)
+		return fmt.Errorf("%w: %s -> %s", ErrSymlinkEscape, name, linkname)
+	}
+
+	e.resp.TotalFiles++
+	return nil
+}
+
+// verifiableLinkTarget reports whether a symlink target can be checked lexically: it must be relative and made of
+// leading ".." components followed by names only. A ".." after a name could step back out of a symlink, e.g.
+// "b/../.." where b is itself a link, which a lexical check cannot see, including for links extracted later.
+func verifiableLinkTarget(linkname string) bool {
+	if linkname == "" || filepath.IsAbs(linkname) || filepath.VolumeName(linkname) != "" {
+		return false
+	}
+
+	named := false
+	for _, part := range strings.Split(filepath.FromSlash(linkname), string(filepath.Separator)) {
+		switch part {
+		case "", ".":
+		case "..":
+			if named {
+				return false
+			}
+		default:
+			named = true
+		}
+	}
+	return true
+}
+
+func (e *extractor) hardlink(name, linkname string) error {
+	if err := e.countEntry(); err != nil {
+		return err
+	}
+
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	source, err := e.target(linkname)
+	if err != nil {
+		return fmt.Errorf("%w: %s -> %s", ErrSymlinkEscape, name, linkname)
+	}
+
+	// Linking to a symlink could alias a file outside destDir.
+	if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s -> %s", ErrSymlinkEscape, name, linkname)
+	}
+
+	if err := removeExisting(path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+	if err := os.Link(source, path); err != nil {
+		return fmt.Errorf("failed to create hard link %s: %w", name, err)
+	}
+
+	e.resp.TotalFiles++
+	return nil
+}
+
+// finish applies directory modification times and permissions deepest first, once no more children will be written.
+func (e *extractor) finish() error {
+	for i := len(e.dirs) - 1; i >= 0; i-- {
+		d := e.dirs[i]
+		if e.opts.PreserveModTime {
+			if err := os.Chtimes(d.path, d.modTime, d.modTime); err != nil {
+				return fmt.Errorf("failed to set modification time on %s: %w", d.path, err)
+			}
+		}
+		if e.opts.PreservePermissions {
+			if err := os.Chmod(d.path, d.mode); err != nil {
+				return fmt.Errorf("failed to set permissions on %s: %w", d.path, err)
+			}
+		}
+	}
+	return nil
+}
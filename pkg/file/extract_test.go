@@ -0,0 +1,275 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildTar writes the given headers (with optional bodies for regular files) into an uncompressed tarball.
+func buildTar(t *testing.T, entries []*tar.Header, bodies map[string]string) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range entries {
+		body := bodies[h.Name]
+		if h.Typeflag == tar.TypeReg && h.Size == 0 {
+			h.Size = int64(len(body))
+		}
+		require.NoError(t, tw.WriteHeader(h))
+		if body != "" {
+			_, err := tw.Write([]byte(body))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	tests := map[string]ArchiveFormat{
+		"backup.zip":     ArchiveFormatZip,
+		"backup.tar":     ArchiveFormatTar,
+		"backup.TAR.GZ":  ArchiveFormatTarGz,
+		"backup.tgz":     ArchiveFormatTarGz,
+		"backup.tar.zst": ArchiveFormatTarZst,
+	}
+	for name, expected := range tests {
+		format, err := DetectArchiveFormat(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, format, name)
+	}
+
+	_, err := DetectArchiveFormat("backup.rar")
+	require.ErrorIs(t, err, ErrUnsupportedArchiveFormat)
+}
+
+func TestExtractArchive(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveFormatZip, ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTarZst} {
+		t.Run("round trip "+string(format), func(t *testing.T) {
+			src := createArchiveFixture(t)
+			archivePath := filepath.Join(t.TempDir(), "backup"+format.Extension())
+
+			out, err := os.Create(archivePath)
+			require.NoError(t, err)
+			_, err = ArchiveDirTo(t.Context(), src, out, ArchiveOptions{Format: format})
+			require.NoError(t, err)
+			require.NoError(t, out.Close())
+
+			dest := t.TempDir()
+			resp, err := ExtractArchive(t.Context(), archivePath, dest, ExtractOptions{
+				PreservePermissions: true,
+				PreserveModTime:     true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, int64(len("data")+len("log")), resp.TotalSize)
+
+			content, err := os.ReadFile(filepath.Join(dest, "sub", "data.txt"))
+			require.NoError(t, err)
+			require.Equal(t, "data", string(content))
+
+			info, err := os.Stat(filepath.Join(dest, "sub", "data.txt"))
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+			require.True(t, info.ModTime().Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+			if format == ArchiveFormatZip {
+				// zip archives only hold regular files
+				require.Equal(t, 2, resp.TotalFiles)
+				return
+			}
+
+			require.Equal(t, 3, resp.TotalFiles)
+			require.Equal(t, 2, resp.TotalDirs)
+			link, err := os.Readlink(filepath.Join(dest, "link"))
+			require.NoError(t, err)
+			require.Equal(t, filepath.Join("sub", "data.txt"), link)
+			require.DirExists(t, filepath.Join(dest, "sub", "empty"))
+		})
+	}
+
+	t.Run("read-only directory", func(t *testing.T) {
+		buf := buildTar(t, []*tar.Header{
+			{Name: "ro/", Typeflag: tar.TypeDir, Mode: 0o555},
+			{Name: "ro/nested/", Typeflag: tar.TypeDir, Mode: 0o755},
+			{Name: "ro/nested/data.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+			{Name: "ro/data.txt", Typeflag: tar.TypeReg, Mode: 0o444},
+		}, map[string]string{"ro/nested/data.txt": "nested", "ro/data.txt": "data"})
+
+		dest := t.TempDir()
+		t.Cleanup(func() {
+			_ = os.Chmod(filepath.Join(dest, "ro"), 0o755)
+		})
+		resp, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{
+			Format:              ArchiveFormatTar,
+			PreservePermissions: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.TotalFiles)
+		require.FileExists(t, filepath.Join(dest, "ro", "nested", "data.txt"))
+
+		info, err := os.Stat(filepath.Join(dest, "ro"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o555), info.Mode().Perm())
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		for _, name := range []string{"../evil.txt", "/etc/evil.txt", "sub/../../evil.txt"} {
+			buf := buildTar(t, []*tar.Header{{Name: name, Typeflag: tar.TypeReg, Mode: 0o644}}, map[string]string{name: "evil"})
+
+			dest := filepath.Join(t.TempDir(), "dest")
+			_, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{Format: ArchiveFormatTar})
+			require.ErrorIs(t, err, ErrPathTraversal, name)
+			require.NoFileExists(t, filepath.Join(filepath.Dir(dest), "evil.txt"))
+		}
+	})
+
+	t.Run("zip slip", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("../evil.txt")
+		require.NoError(t, err)
+		_, err = w.Write([]byte("evil"))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		archivePath := filepath.Join(t.TempDir(), "evil.zip")
+		require.NoError(t, os.WriteFile(archivePath, buf.Bytes(), 0o644))
+
+		_, err = ExtractArchive(t.Context(), archivePath, filepath.Join(t.TempDir(), "dest"), ExtractOptions{})
+		require.ErrorIs(t, err, ErrPathTraversal)
+	})
+
+	t.Run("symlink escape", func(t *testing.T) {
+		tests := map[string][]*tar.Header{
+			"absolute target": {
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+			},
+			"relative target": {
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../outside"},
+			},
+			"dot dot after symlink": {
+				{Name: "self", Typeflag: tar.TypeSymlink, Linkname: "."},
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "self/../outside"},
+			},
+			"hard link": {
+				{Name: "link", Typeflag: tar.TypeLink, Linkname: "../outside"},
+			},
+		}
+		for name, headers := range tests {
+			t.Run(name, func(t *testing.T) {
+				root := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(root, "outside"), []byte("secret"), 0o644))
+
+				buf := buildTar(t, headers, nil)
+				dest := filepath.Join(root, "dest")
+				_, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{Format: ArchiveFormatTar})
+				require.Error(t, err)
+				require.NoFileExists(t, filepath.Join(dest, "link"))
+			})
+		}
+	})
+
+	t.Run("chained symlinks", func(t *testing.T) {
+		root := t.TempDir()
+		dest := filepath.Join(root, "dest")
+
+		// A dangles when extracted and only escapes through B, which stays inside dest.
+		buf := buildTar(t, []*tar.Header{
+			{Name: "d1/d2/A", Typeflag: tar.TypeSymlink, Linkname: "B/../../.."},
+			{Name: "d1/d2/B", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+			{Name: "d1/d2/A/escaped/x", Typeflag: tar.TypeReg},
+		}, map[string]string{"d1/d2/A/escaped/x": "evil"})
+
+		_, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{Format: ArchiveFormatTar})
+		require.ErrorIs(t, err, ErrSymlinkEscape)
+		require.NoFileExists(t, filepath.Join(dest, "d1", "d2", "A"))
+		require.NoDirExists(t, filepath.Join(dest, "..", "..", "..", "escaped"))
+	})
+
+	t.Run("relative symlinks inside dest", func(t *testing.T) {
+		buf := buildTar(t, []*tar.Header{
+			{Name: "lib/data.txt", Typeflag: tar.TypeReg},
+			{Name: "bin/link", Typeflag: tar.TypeSymlink, Linkname: "../lib/data.txt"},
+			{Name: "alias", Typeflag: tar.TypeSymlink, Linkname: "./lib"},
+			{Name: "alias/nested/file.txt", Typeflag: tar.TypeReg},
+		}, map[string]string{"lib/data.txt": "data", "alias/nested/file.txt": "nested"})
+
+		dest := t.TempDir()
+		_, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{Format: ArchiveFormatTar})
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dest, "bin", "link"))
+		require.NoError(t, err)
+		require.Equal(t, "data", string(content))
+		require.FileExists(t, filepath.Join(dest, "lib", "nested", "file.txt"))
+	})
+
+	t.Run("write through symlinked dir", func(t *testing.T) {
+		root := t.TempDir()
+		dest := filepath.Join(root, "dest")
+		require.NoError(t, os.MkdirAll(dest, 0o755))
+		require.NoError(t, os.Symlink(root, filepath.Join(dest, "escape")))
+
+		buf := buildTar(t, []*tar.Header{{Name: "escape/evil.txt", Typeflag: tar.TypeReg}}, map[string]string{"escape/evil.txt": "evil"})
+		_, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{Format: ArchiveFormatTar})
+		require.ErrorIs(t, err, ErrPathTraversal)
+		require.NoFileExists(t, filepath.Join(root, "evil.txt"))
+	})
+
+	t.Run("max total size", func(t *testing.T) {
+		buf := buildTar(t, []*tar.Header{
+			{Name: "a.txt", Typeflag: tar.TypeReg},
+			{Name: "b.txt", Typeflag: tar.TypeReg},
+		}, map[string]string{"a.txt": "12345", "b.txt": "67890"})
+
+		resp, err := ExtractArchiveFrom(t.Context(), buf, t.TempDir(), ExtractOptions{Format: ArchiveFormatTar, MaxTotalSize: 8})
+		require.ErrorIs(t, err, ErrArchiveTooLarge)
+		require.Equal(t, 1, resp.TotalFiles)
+	})
+
+	t.Run("max files", func(t *testing.T) {
+		buf := buildTar(t, []*tar.Header{
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0o755},
+			{Name: "a/b.txt", Typeflag: tar.TypeReg},
+		}, map[string]string{"a/b.txt": "b"})
+
+		_, err := ExtractArchiveFrom(t.Context(), buf, t.TempDir(), ExtractOptions{Format: ArchiveFormatTar, MaxFiles: 1})
+		require.ErrorIs(t, err, ErrArchiveTooLarge)
+	})
+
+	t.Run("default permissions", func(t *testing.T) {
+		buf := buildTar(t, []*tar.Header{{Name: "script.sh", Typeflag: tar.TypeReg, Mode: 0o4777}}, map[string]string{"script.sh": "#!/bin/sh"})
+
+		dest := t.TempDir()
+		_, err := ExtractArchiveFrom(t.Context(), buf, dest, ExtractOptions{Format: ArchiveFormatTar})
+		require.NoError(t, err)
+
+		info, err := os.Stat(filepath.Join(dest, "script.sh"))
+		require.NoError(t, err)
+		require.Zero(t, info.Mode()&(os.ModeSetuid|0o111))
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		buf := buildTar(t, []*tar.Header{{Name: "a.txt", Typeflag: tar.TypeReg}}, map[string]string{"a.txt": "a"})
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		_, err := ExtractArchiveFrom(ctx, buf, t.TempDir(), ExtractOptions{Format: ArchiveFormatTar})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("zip from stream", func(t *testing.T) {
+		_, err := ExtractArchiveFrom(t.Context(), &bytes.Buffer{}, t.TempDir(), ExtractOptions{Format: ArchiveFormatZip})
+		require.ErrorIs(t, err, ErrUnsupportedArchiveFormat)
+	})
+}
@@ -3,14 +3,11 @@ package file
 
 import (
 	"archive/tar"
-	"archive/zip"
 	"bufio"
 	"compress/gzip"
-	"context"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -28,106 +25,6 @@ func shouldExclude(name string, exclude []*regexp.Regexp) bool {
 	return false
 }
 
-// ArchiveDirResponse represents the result of archiving a directory.
-type ArchiveDirResponse struct {
-	ArchivePath  string
-	TotalFiles   int
-	TotalDirs    int
-	SuccessFiles int
-	FailedFiles  map[string]error
-}
-
-// ArchiveDir creates a zip archive of the specified directory, excluding files/dirs matching the exclude patterns.
-func ArchiveDir(dirPath string, exclude []*regexp.Regexp) (ArchiveDirResponse, error) {
-	dirPath = filepath.Clean(dirPath)
-	dirName := filepath.Base(dirPath)
-	zipName := fmt.Sprintf("%s.zip", dirName)
-	zipPath := filepath.Join(os.TempDir(), zipName)
-
-	zipFile, err := os.Create(zipPath)
-	if err != nil {
-		return ArchiveDirResponse{}, fmt.Errorf("failed to create zip file: %w", err)
-	}
-	defer func() {
-		_ = zipFile.Close()
-	}()
-
-	zipWriter := zip.NewWriter(zipFile)
-	defer func() {
-		_ = zipWriter.Close()
-	}()
-
-	totalFiles, totalDirs, successFiles := 0, 0, 0
-	failedFiles := make(map[string]error)
-
-	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("walk error at %s: %w", path, err)
-		}
-
-		if info.IsDir() {
-			if shouldExclude(info.Name(), exclude) {
-				slog.Info("Skipping dir", "name", info.Name(), "path", path)
-				return filepath.SkipDir
-			}
-			totalDirs++
-			return nil
-		}
-
-		if shouldExclude(info.Name(), exclude) {
-			slog.Info("Skipping file", "name", info.Name(), "path", path)
-			return nil
-		}
-
-		totalFiles++
-
-		if !info.Mode().IsRegular() {
-			return nil
-		}
-
-		relPath, err := filepath.Rel(dirPath, path)
-		if err != nil {
-			failedFiles[path] = fmt.Errorf("failed to get relative path: %w", err)
-			return nil
-		}
-
-		zh, err := zipWriter.CreateHeader(&zip.FileHeader{
-			Name:   relPath,
-			Method: zip.Deflate,
-		})
-		if err != nil {
-			failedFiles[path] = fmt.Errorf("failed to create zip header: %w", err)
-			return nil
-		}
-
-		file, err := os.Open(path)
-		if err != nil {
-			failedFiles[path] = fmt.Errorf("failed to open file: %w", err)
-			return nil
-		}
-		defer func() {
-			_ = file.Close()
-		}()
-
-		_, err = io.Copy(zh, file)
-		if err != nil {
-			failedFiles[path] = fmt.Errorf("failed to copy file to zip: %w", err)
-			return nil
-		}
-
-		successFiles++
-		return nil
-	})
-
-	return ArchiveDirResponse{
-		ArchivePath:  zipPath,
-		TotalFiles:   totalFiles,
-		TotalDirs:    totalDirs,
-		SuccessFiles: successFiles,
-		FailedFiles:  failedFiles,
-	}, err
-}
-
 // ReadFileBytes reads the entire content of a file and returns it as a byte slice.
 func ReadFileBytes(path string) ([]byte, error) {
 	file, err := os.Open(path)
@@ -168,40 +65,6 @@ func ReadFileLines(path string) ([]string, error) {
 	return lines, nil
 }
 
-// DownloadFile downloads a file from the given URL to the specified destination path.
-func DownloadFile(ctx context.Context, url string, destination string) error {
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return err
-	}
-	response, err := http.DefaultClient.Do(request)
-	if err != nil {
-		return fmt.Errorf("failed to get url: %w", err)
-	}
-	defer func() {
-		_ = response.Body.Close()
-	}()
-
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", response.Status)
-	}
-
-	out, err := os.Create(destination)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer func() {
-		_ = out.Close()
-	}()
-
-	_, err = io.Copy(out, response.Body)
-	if err != nil {
-		return fmt.Errorf("failed to copy response body: %w", err)
-	}
-
-	return nil
-}
-
 // ExtractFileFromTarGz extracts a specific file from a .tar.gz archive and returns the path to the extracted file.
 func ExtractFileFromTarGz(archivePath, targetFilename string) (string, error) {
 	var targetFilePath string
@@ -235,7 +98,7 @@ func ExtractFileFromTarGz(archivePath, targetFilename string) (string, error) {
 
 		if strings.HasSuffix(header.Name, targetFilename) {
 			targetFilePath = filepath.Join(os.TempDir(), targetFilename)
-			targetFile, err := os.OpenFile(targetFilePath, os.O_CREATE|os.O_WRONLY, header.FileInfo().Mode())
+			targetFile, err := NewAtomicWriter(targetFilePath, AtomicWriteOptions{Perm: header.FileInfo().Mode().Perm()})
 			if err != nil {
 				return targetFilePath, fmt.Errorf("failed to create target file: %w", err)
 			}
@@ -244,9 +107,11 @@ func ExtractFileFromTarGz(archivePath, targetFilename string) (string, error) {
 			}()
 
 			if _, err := io.CopyN(targetFile, tarReader, header.Size); err != nil {
-				_ = os.Remove(targetFilePath)
 				return targetFilePath, fmt.Errorf("failed to copy file from archive: %w", err)
 			}
+			if err := targetFile.Commit(); err != nil {
+				return targetFilePath, fmt.Errorf("failed to write target file: %w", err)
+			}
 			break
 		}
 	}
@@ -2,8 +2,6 @@ package file
 
 import (
 	"archive/zip"
-	"net/http"
-	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -182,56 +180,6 @@ func TestReadFileLines(t *testing.T) {
 	})
 }
 
-func TestDownloadFile(t *testing.T) {
-	t.Run("Valid Download", func(t *testing.T) {
-		// Create a test file
-		_, absPath, err := testhelper.CreateTestFile(t.TempDir(), "")
-		require.NoError(t, err)
-
-		// Create a mock HTTP server
-		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Serve a test file
-			http.ServeFile(w, r, absPath)
-		}))
-		defer server.Close()
-
-		downloadFilePath := filepath.Join(t.TempDir(), "test-file.txt")
-		t.Cleanup(func() {
-			_ = os.Remove(downloadFilePath)
-		})
-
-		// Download the file using the download function
-		err = DownloadFile(t.Context(), server.URL, downloadFilePath)
-		require.NoError(t, err)
-
-		lines, err := ReadFileLines(downloadFilePath)
-		require.NoError(t, err)
-		require.Len(t, lines, 2)
-	})
-
-	t.Run("Invalid Download", func(t *testing.T) {
-		// Create a mock HTTP server
-		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Serve a test file
-			http.ServeFile(w, r, "some/random/path")
-		}))
-		defer server.Close()
-
-		downloadFilePath := filepath.Join(t.TempDir(), "test-file.txt")
-		t.Cleanup(func() {
-			_ = os.Remove(downloadFilePath)
-		})
-
-		// Download the file using the download function
-		err := DownloadFile(t.Context(), server.URL, downloadFilePath)
-		require.Error(t, err)
-
-		lines, err := ReadFileLines(downloadFilePath)
-		require.Error(t, err)
-		require.Nil(t, lines)
-	})
-}
-
 func TestExtractFileFromTarGz(t *testing.T) {
 	t.Run("Valid Extraction", func(t *testing.T) {
 		archivePath := filepath.Join(testhelper.TestDataDir, "sample.tar.gz")
@@ -0,0 +1,199 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLockRetryInterval is the wait between attempts of Lock.
+const DefaultLockRetryInterval = 100 * time.Millisecond
+
+// LockOptions configures Lock.
+type LockOptions struct {
+	// Timeout bounds the wait for the lock; zero waits until the context is done.
+	Timeout time.Duration
+	// RetryInterval defaults to DefaultLockRetryInterval.
+	RetryInterval time.Duration
+}
+
+// FileLock is an exclusive advisory lock on a file holding the PID of its owner, used to guarantee
+// single-instance execution of jobs. It is an flock(2) lock where available, released by the kernel
+// when the owner dies. Elsewhere, the lock is the exclusive creation of the file, and a file left
+// behind by a process that no longer runs is detected as stale and replaced.
+type FileLock struct {
+	path string
+	f    *os.File
+}
+
+// TryLock acquires the lock at path without waiting. It returns ErrLocked, with the owner's PID when known,
+// if another process holds it.
+func TryLock(path string) (*FileLock, error) {
+	f, err := acquireLock(path)
+	if err != nil {
+		if !errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if pid := readLockPID(path); pid > 0 {
+			return nil, fmt.Errorf("%w: %s (pid %d)", ErrLocked, path, pid)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+
+	if err := writeLockPID(f); err != nil {
+		_ = os.Remove(path)
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to write lock pid: %w", err)
+	}
+
+	return &FileLock{path: path, f: f}, nil
+}
+
+// Lock acquires the lock at path, retrying until it is released, the timeout expires or ctx is done.
+func Lock(ctx context.Context, path string, opts LockOptions) (*FileLock, error) {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultLockRetryInterval
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	for {
+		lock, err := TryLock(path)
+		if err == nil || !errors.Is(err, ErrLocked) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", err, ctx.Err())
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+}
+
+// Path returns the path of the lock file.
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock removes the lock file and releases the lock.
+func (l *FileLock) Unlock() error {
+	if l.f == nil {
+		return os.ErrClosed
+	}
+
+	// Remove while still holding the lock; a waiter that opened the old file notices it was replaced.
+	removeErr := os.Remove(l.path)
+	closeErr := l.f.Close()
+	l.f = nil
+
+	if removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", removeErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to release lock: %w", closeErr)
+	}
+	return nil
+}
+
+// pidLockWriteGrace is how long a lock file without a PID is left to its owner, which writes it right
+// after creating the file, before it is considered stale.
+const pidLockWriteGrace = 10 * time.Second
+
+// acquirePIDLock locks by exclusively creating the file, replacing it when its owner no longer runs.
+func acquirePIDLock(path string) (*os.File, error) {
+	for range 2 {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		removed, err := removeStalePIDLock(path)
+		if err != nil {
+			return nil, err
+		}
+		if !removed {
+			return nil, ErrLocked
+		}
+	}
+	return nil, ErrLocked
+}
+
+// removeStalePIDLock removes the lock file at path when its owner no longer runs, or when it still holds
+// no PID after pidLockWriteGrace. The check and the removal are done while holding a guard file created
+// exclusively, so two waiters cannot both remove the stale file and then each other's new lock file.
+func removeStalePIDLock(path string) (bool, error) {
+	guardPath := path + ".stale"
+	guard, err := os.OpenFile(guardPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if !errors.Is(err, fs.ErrExist) {
+			return false, err
+		}
+		// Another waiter is replacing the file. A guard left behind by a crash expires like a lock without PID.
+		if info, err := os.Stat(guardPath); err == nil && time.Since(info.ModTime()) > pidLockWriteGrace {
+			_ = os.Remove(guardPath)
+		}
+		return false, nil
+	}
+	_ = guard.Close()
+	defer func() {
+		_ = os.Remove(guardPath)
+	}()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	pid := readLockPID(path)
+	if pid > 0 && processAlive(pid) {
+		return false, nil
+	}
+	if pid <= 0 && time.Since(info.ModTime()) < pidLockWriteGrace {
+		return false, nil
+	}
+
+	slog.Warn("Removing stale lock", "path", path, "pid", pid)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	return true, nil
+}
+
+func writeLockPID(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// readLockPID returns the PID stored in a lock file, or 0 when it cannot be read.
+func readLockPID(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
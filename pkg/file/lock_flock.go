@@ -0,0 +1,46 @@
+//go:build unix && !aix
+
+package file
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func acquireLock(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		err = unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+		switch {
+		case err == nil:
+		case errors.Is(err, unix.EWOULDBLOCK):
+			_ = f.Close()
+			return nil, ErrLocked
+		case errors.Is(err, unix.ENOLCK), errors.Is(err, unix.EOPNOTSUPP):
+			// Some network filesystems do not support flock.
+			_ = f.Close()
+			return acquirePIDLock(path)
+		default:
+			_ = f.Close()
+			return nil, err
+		}
+
+		// The previous owner may have removed the file between open and flock, retry on the new file.
+		locked, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		current, err := os.Stat(path)
+		if err == nil && os.SameFile(locked, current) {
+			return f, nil
+		}
+		_ = f.Close()
+	}
+}
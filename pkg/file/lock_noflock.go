@@ -0,0 +1,10 @@
+//go:build !unix || aix
+
+package file
+
+import "os"
+
+// acquireLock falls back to PID file locks on platforms without flock.
+func acquireLock(path string) (*os.File, error) {
+	return acquirePIDLock(path)
+}
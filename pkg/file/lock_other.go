@@ -0,0 +1,16 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists. Platforms where
+// os.FindProcess always succeeds report every process as alive, so locks are never considered stale.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,168 @@
+package file
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+
+	lock, err := TryLock(path)
+	require.NoError(t, err)
+	require.Equal(t, path, lock.Path())
+	require.Equal(t, os.Getpid(), readLockPID(path))
+
+	_, err = TryLock(path)
+	require.ErrorIs(t, err, ErrLocked)
+	require.Contains(t, err.Error(), "pid "+strconv.Itoa(os.Getpid()))
+
+	require.NoError(t, lock.Unlock())
+	require.NoFileExists(t, path)
+	require.ErrorIs(t, lock.Unlock(), os.ErrClosed)
+
+	lock, err = TryLock(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
+
+func TestLock(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "job.lock")
+		held, err := TryLock(path)
+		require.NoError(t, err)
+		defer func() {
+			_ = held.Unlock()
+		}()
+
+		_, err = Lock(t.Context(), path, LockOptions{Timeout: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+		require.ErrorIs(t, err, ErrLocked)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("waits for release", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "job.lock")
+		held, err := TryLock(path)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = held.Unlock()
+		}()
+
+		lock, err := Lock(t.Context(), path, LockOptions{Timeout: 5 * time.Second, RetryInterval: 10 * time.Millisecond})
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+	})
+
+	t.Run("leftover file is reused", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "job.lock")
+		require.NoError(t, os.WriteFile(path, []byte("1\n"), 0o644))
+
+		lock, err := Lock(t.Context(), path, LockOptions{})
+		require.NoError(t, err)
+		require.Equal(t, os.Getpid(), readLockPID(path))
+		require.NoError(t, lock.Unlock())
+	})
+}
+
+func TestAcquirePIDLock(t *testing.T) {
+	t.Run("held by running process", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "job.lock")
+		require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0o644))
+
+		_, err := acquirePIDLock(path)
+		require.ErrorIs(t, err, ErrLocked)
+	})
+
+	t.Run("being written", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "job.lock")
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+		_, err := acquirePIDLock(path)
+		require.ErrorIs(t, err, ErrLocked)
+	})
+
+	t.Run("left without pid", func(t *testing.T) {
+		for _, content := range []string{"", "garbage"} {
+			path := filepath.Join(t.TempDir(), "job.lock")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			old := time.Now().Add(-2 * pidLockWriteGrace)
+			require.NoError(t, os.Chtimes(path, old, old))
+
+			f, err := acquirePIDLock(path)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			require.NoFileExists(t, path+".stale")
+		}
+	})
+
+	t.Run("being replaced by another waiter", func(t *testing.T) {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		require.NoError(t, cmd.Run())
+
+		path := filepath.Join(t.TempDir(), "job.lock")
+		require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0o644))
+		require.NoError(t, os.WriteFile(path+".stale", nil, 0o644))
+
+		_, err := acquirePIDLock(path)
+		require.ErrorIs(t, err, ErrLocked)
+		require.FileExists(t, path)
+
+		// A guard left behind by a crash expires.
+		old := time.Now().Add(-2 * pidLockWriteGrace)
+		require.NoError(t, os.Chtimes(path+".stale", old, old))
+		_, err = acquirePIDLock(path)
+		require.ErrorIs(t, err, ErrLocked)
+		f, err := acquirePIDLock(path)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	})
+
+	t.Run("concurrent waiters", func(t *testing.T) {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		require.NoError(t, cmd.Run())
+
+		for range 20 {
+			path := filepath.Join(t.TempDir(), "job.lock")
+			require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0o644))
+
+			var acquired atomic.Int32
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Go(func() {
+					if f, err := acquirePIDLock(path); err == nil {
+						acquired.Add(1)
+						_ = writeLockPID(f)
+						_ = f.Close()
+					}
+				})
+			}
+			wg.Wait()
+			require.Equal(t, int32(1), acquired.Load())
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		require.NoError(t, cmd.Run())
+
+		path := filepath.Join(t.TempDir(), "job.lock")
+		require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0o644))
+
+		f, err := acquirePIDLock(path)
+		require.NoError(t, err)
+		require.NoError(t, writeLockPID(f))
+		require.NoError(t, f.Close())
+		require.Equal(t, os.Getpid(), readLockPID(path))
+	})
+}
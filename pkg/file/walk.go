@@ -0,0 +1,405 @@
+package file
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"strings"
+	"time"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+)
+
+// maxSymlinkHops bounds the number of links resolved for a single path, like the OS ELOOP limit.
+const maxSymlinkHops = 40
+
+// SymlinkPolicy controls how Walk handles symbolic links.
+type SymlinkPolicy string
+
+const (
+	// SymlinkReport reports links as entries without following them. This is the default.
+	SymlinkReport SymlinkPolicy = "report"
+
+	// SymlinkIgnore skips links entirely.
+	SymlinkIgnore SymlinkPolicy = "ignore"
+
+	// SymlinkFollow reports the link targets and walks linked directories.
+	// Dangling links and links leaving the file system are reported as links; loops are not walked twice.
+	SymlinkFollow SymlinkPolicy = "follow"
+)
+
+// WalkOptions configures Walk. Glob patterns use path.Match syntax on slash separated paths relative
+// to the walk root, plus `**` matching any number of directories. A pattern without a slash matches
+// the base name at any depth.
+type WalkOptions struct {
+	// Include limits the reported files to those matching any of the globs. Directories are always walked.
+	Include []string
+	// Exclude skips files and directories matching any of the globs.
+	Exclude []string
+	// IgnoreFileName, e.g. ".gitignore", names files whose gitignore-style rules apply to their directory tree.
+	IgnoreFileName string
+	// MinSize skips files smaller than this many bytes.
+	MinSize int64
+	// MaxSize, when set, skips files larger than this many bytes.
+	MaxSize int64
+	// ModifiedSince, when set, skips files modified before this time.
+	ModifiedSince time.Time
+	// Symlinks defaults to SymlinkReport.
+	Symlinks SymlinkPolicy
+	// IncludeDirs also reports the directories below root.
+	IncludeDirs bool
+}
+
+// WalkEntry is a file or directory reported by Walk.
+type WalkEntry struct {
+	// Path is the path within the file system, usable with fs.ReadFile.
+	Path string
+	// RelPath is the slash separated path relative to the walk root.
+	RelPath string
+	// Info describes the entry; for followed links it describes the target.
+	Info fs.FileInfo
+}
+
+// Walk streams the entries below root in fsys in lexical order, skipping excluded and ignored paths.
+// Use os.DirFS to walk the OS file system. Errors reading a directory are yielded with the directory path
+// and its subtree is skipped; the walk continues unless the consumer stops iterating.
+func Walk(fsys fs.FS, root string, opts WalkOptions) iter.Seq2[WalkEntry, error] {
+	return func(yield func(WalkEntry, error) bool) {
+		for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+			if err := validateGlob(pattern); err != nil {
+				yield(WalkEntry{}, err)
+				return
+			}
+		}
+		if opts.Symlinks == "" {
+			opts.Symlinks = SymlinkReport
+		}
+
+		realRoot := root
+		if opts.Symlinks == SymlinkFollow {
+			resolved, err := resolveLinks(fsys, root)
+			if err != nil {
+				yield(WalkEntry{Path: root}, fmt.Errorf("failed to resolve %s: %w", root, err))
+				return
+			}
+			realRoot = resolved
+		}
+
+		info, err := fs.Stat(fsys, realRoot)
+		if err != nil {
+			yield(WalkEntry{Path: root}, err)
+			return
+		}
+		if !info.IsDir() {
+			yield(WalkEntry{Path: root}, fmt.Errorf("%w: %s", commonErrors.ErrNotDir, root))
+			return
+		}
+
+		w := &walker{
+			fsys:   fsys,
+			opts:   opts,
+			yield:  yield,
+			active: make(map[string]bool),
+		}
+		w.walkDir(root, "", realRoot, nil)
+	}
+}
+
+type walker struct {
+	fsys  fs.FS
+	opts  WalkOptions
+	yield func(WalkEntry, error) bool
+	// active holds the resolved directories being walked, to detect link loops.
+	active map[string]bool
+}
+
+// walkDir reports the content of a directory; dirPath is its path as reported, realPath its path without links.
+// It returns false once the consumer stopped the iteration.
+func (w *walker) walkDir(dirPath, relDir, realPath string, rules []ignoreRule) bool {
+	w.active[realPath] = true
+	defer delete(w.active, realPath)
+
+	if w.opts.IgnoreFileName != "" {
+		dirRules, err := readIgnoreFile(w.fsys, path.Join(realPath, w.opts.IgnoreFileName), relDir)
+		if err != nil {
+			if !w.yield(WalkEntry{Path: dirPath, RelPath: relDir}, err) {
+				return false
+			}
+		}
+		// Copy so sibling directories do not share appended rules.
+		rules = append(append([]ignoreRule{}, rules...), dirRules...)
+	}
+
+	entries, err := fs.ReadDir(w.fsys, realPath)
+	if err != nil {
+		return w.yield(WalkEntry{Path: dirPath, RelPath: relDir}, fmt.Errorf("failed to read dir %s: %w", dirPath, err))
+	}
+
+	for _, entry := range entries {
+		entryPath := path.Join(dirPath, entry.Name())
+		entryReal := path.Join(realPath, entry.Name())
+		relPath := entry.Name()
+		if relDir != "" {
+			relPath = relDir + "/" + entry.Name()
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed while walking.
+				continue
+			}
+			if !w.yield(WalkEntry{Path: entryPath, RelPath: relPath}, err) {
+				return false
+			}
+			continue
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			switch w.opts.Symlinks {
+			case SymlinkIgnore:
+				continue
+			case SymlinkFollow:
+				if resolved, err := resolveLinks(w.fsys, entryReal); err == nil {
+					if target, err := fs.Stat(w.fsys, resolved); err == nil {
+						info = target
+						entryReal = resolved
+					}
+				}
+			}
+		}
+
+		isDir := info.IsDir()
+		if matchAnyGlob(w.opts.Exclude, relPath) || isIgnored(rules, relPath, isDir) {
+			continue
+		}
+
+		if isDir {
+			if w.active[entryReal] {
+				// A link back into a directory being walked.
+				continue
+			}
+			if w.opts.IncludeDirs && !w.yield(WalkEntry{Path: entryPath, RelPath: relPath, Info: info}, nil) {
+				return false
+			}
+			if !w.walkDir(entryPath, relPath, entryReal, rules) {
+				return false
+			}
+			continue
+		}
+
+		if !w.matches(relPath, info) {
+			continue
+		}
+		if !w.yield(WalkEntry{Path: entryPath, RelPath: relPath, Info: info}, nil) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches applies the include globs and the size and time filters to a file.
+func (w *walker) matches(relPath string, info fs.FileInfo) bool {
+	if len(w.opts.Include) > 0 && !matchAnyGlob(w.opts.Include, relPath) {
+		return false
+	}
+	if info.Size() < w.opts.MinSize {
+		return false
+	}
+	if w.opts.MaxSize > 0 && info.Size() > w.opts.MaxSize {
+		return false
+	}
+	if !w.opts.ModifiedSince.IsZero() && info.ModTime().Before(w.opts.ModifiedSince) {
+		return false
+	}
+	return true
+}
+
+// resolveLinks returns name with every symbolic link component replaced by its target.
+func resolveLinks(fsys fs.FS, name string) (string, error) {
+	parts := strings.Split(name, "/")
+	resolved := "."
+	hops := 0
+
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		if part == "" || part == "." {
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		if !fs.ValidPath(next) {
+			return "", fmt.Errorf("%w: %s leaves the file system", fs.ErrInvalid, name)
+		}
+
+		info, err := fs.Lstat(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", fmt.Errorf("%w: too many links in %s", fs.ErrInvalid, name)
+		}
+		target, err := fs.ReadLink(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			return "", fmt.Errorf("%w: %s leaves the file system", fs.ErrInvalid, name)
+		}
+		parts = append(strings.Split(target, "/"), parts...)
+	}
+
+	return resolved, nil
+}
+
+// validateGlob reports malformed patterns up front, since path.Match only reports them when reached.
+func validateGlob(pattern string) error {
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchAnyGlob(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash separated relative path; see WalkOptions for the pattern syntax.
+func matchGlob(pattern, relPath string) bool {
+	segments := strings.Split(relPath, "/")
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		return matchSegments([]string{"**", strings.TrimSuffix(pattern, "/")}, segments)
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), segments)
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// ignoreRule is a line of a gitignore-style file.
+type ignoreRule struct {
+	// base is the directory of the ignore file, relative to the walk root.
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+func (r ignoreRule) match(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		sub, ok := strings.CutPrefix(relPath, r.base+"/")
+		if !ok {
+			return false
+		}
+		relPath = sub
+	}
+
+	segments := strings.Split(relPath, "/")
+	if r.anchored {
+		return matchSegments(strings.Split(r.pattern, "/"), segments)
+	}
+	return matchSegments([]string{"**", r.pattern}, segments)
+}
+
+// isIgnored applies the rules in order, the last matching rule wins.
+func isIgnored(rules []ignoreRule, relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.match(relPath, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// readIgnoreFile parses a gitignore-style file; a missing file yields no rules.
+func readIgnoreFile(fsys fs.FS, name, base string) ([]ignoreRule, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open ignore file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// A slash at the start or in the middle anchors the pattern to the ignore file's directory.
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" || validateGlob(line) != nil {
+			continue
+		}
+
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	return rules, nil
+}
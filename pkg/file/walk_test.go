@@ -0,0 +1,160 @@
+package file
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// collectWalk returns the RelPath of every walked entry.
+func collectWalk(t *testing.T, fsys fs.FS, root string, opts WalkOptions) []string {
+	t.Helper()
+
+	var paths []string
+	for entry, err := range Walk(fsys, root, opts) {
+		require.NoError(t, err)
+		paths = append(paths, entry.RelPath)
+	}
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"main.go":                {Data: []byte("package main"), ModTime: recent},
+		"README.md":              {Data: []byte("readme"), ModTime: old},
+		"big.bin":                {Data: make([]byte, 1024), ModTime: recent},
+		"pkg/util.go":            {Data: []byte("package pkg"), ModTime: recent},
+		"pkg/util_test.go":       {Data: []byte("package pkg"), ModTime: old},
+		"pkg/internal/deep.go":   {Data: []byte("package internal"), ModTime: recent},
+		"vendor/lib/lib.go":      {Data: []byte("package lib"), ModTime: recent},
+		"build/out.log":          {Data: []byte("log"), ModTime: recent},
+		"build/keep.log":         {Data: []byte("log"), ModTime: recent},
+		".gitignore":             {Data: []byte("# comment\n*.log\n!keep.log\n/vendor/\n")},
+		"pkg/.gitignore":         {Data: []byte("internal/\n")},
+		"docs/guide/index.md":    {Data: []byte("guide"), ModTime: recent},
+		"docs/guide/img/pic.png": {Data: []byte("png"), ModTime: recent},
+	}
+
+	t.Run("all files", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{})
+		require.Len(t, paths, len(fsys))
+		require.Equal(t, ".gitignore", paths[0])
+	})
+
+	t.Run("include globs", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{Include: []string{"**/*.go"}, Exclude: []string{"*_test.go"}})
+		require.Equal(t, []string{"main.go", "pkg/internal/deep.go", "pkg/util.go", "vendor/lib/lib.go"}, paths)
+	})
+
+	t.Run("exclude dir glob", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{Include: []string{"docs/**"}, Exclude: []string{"docs/guide/img"}})
+		require.Equal(t, []string{"docs/guide/index.md"}, paths)
+	})
+
+	t.Run("ignore files", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{IgnoreFileName: ".gitignore", Include: []string{"*.go", "*.log"}})
+		require.Equal(t, []string{"build/keep.log", "main.go", "pkg/util.go", "pkg/util_test.go"}, paths)
+	})
+
+	t.Run("size and time filters", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{MinSize: 4, MaxSize: 512, ModifiedSince: recent})
+		require.Equal(t, []string{"docs/guide/index.md", "main.go", "pkg/internal/deep.go", "pkg/util.go", "vendor/lib/lib.go"}, paths)
+	})
+
+	t.Run("sub root with dirs", func(t *testing.T) {
+		var paths []string
+		for entry, err := range Walk(fsys, "pkg", WalkOptions{IncludeDirs: true}) {
+			require.NoError(t, err)
+			require.Equal(t, "pkg/"+entry.RelPath, entry.Path)
+			paths = append(paths, entry.RelPath)
+		}
+		require.Equal(t, []string{".gitignore", "internal", "internal/deep.go", "util.go", "util_test.go"}, paths)
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		count := 0
+		for range Walk(fsys, ".", WalkOptions{}) {
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, err := range Walk(fsys, ".", WalkOptions{Include: []string{"[a-"}}) {
+			require.ErrorIs(t, err, path.ErrBadPattern)
+		}
+		for _, err := range Walk(fsys, "main.go", WalkOptions{}) {
+			require.ErrorIs(t, err, commonErrors.ErrNotDir)
+		}
+		for _, err := range Walk(fsys, "missing", WalkOptions{}) {
+			require.ErrorIs(t, err, fs.ErrNotExist)
+		}
+	})
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "data", "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data", "nested", "file.txt"), []byte("content"), 0o644))
+	require.NoError(t, os.Symlink("data", filepath.Join(dir, "alias")))
+	require.NoError(t, os.Symlink("../..", filepath.Join(dir, "data", "nested", "loop")))
+	require.NoError(t, os.Symlink("missing", filepath.Join(dir, "dangling")))
+	require.NoError(t, os.Symlink("data/nested/file.txt", filepath.Join(dir, "file-link")))
+	fsys := os.DirFS(dir)
+
+	t.Run("report", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{})
+		require.Equal(t, []string{"alias", "dangling", "data/nested/file.txt", "data/nested/loop", "file-link"}, paths)
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		paths := collectWalk(t, fsys, ".", WalkOptions{Symlinks: SymlinkIgnore})
+		require.Equal(t, []string{"data/nested/file.txt"}, paths)
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		var paths []string
+		for entry, err := range Walk(fsys, ".", WalkOptions{Symlinks: SymlinkFollow}) {
+			require.NoError(t, err)
+			paths = append(paths, entry.RelPath)
+			if entry.RelPath == "file-link" {
+				require.True(t, entry.Info.Mode().IsRegular())
+				require.Equal(t, int64(len("content")), entry.Info.Size())
+			}
+		}
+		require.Equal(t, []string{"alias/nested/file.txt", "dangling", "data/nested/file.txt", "file-link"}, paths)
+	})
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{pattern: "*.go", path: "a/b/c.go", match: true},
+		{pattern: "/*.go", path: "a/c.go", match: false},
+		{pattern: "a/*.go", path: "a/c.go", match: true},
+		{pattern: "a/*.go", path: "a/b/c.go", match: false},
+		{pattern: "a/**/*.go", path: "a/c.go", match: true},
+		{pattern: "a/**/*.go", path: "a/b/c/d.go", match: true},
+		{pattern: "**/testdata", path: "x/testdata", match: true},
+		{pattern: "a/**", path: "a/b/c", match: true},
+		{pattern: "a/**", path: "b/a/c", match: false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.match, matchGlob(tt.pattern, tt.path), "%s ~ %s", tt.pattern, tt.path)
+	}
+}
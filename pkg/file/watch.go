@@ -0,0 +1,538 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// DefaultWatchDebounce is the quiet period after which a burst of events is delivered.
+	DefaultWatchDebounce = 100 * time.Millisecond
+
+	// DefaultWatchPollInterval is the scan interval of the polling watcher.
+	DefaultWatchPollInterval = time.Second
+
+	// watchErrorBuffer is the number of errors kept when Errors is not drained.
+	watchErrorBuffer = 16
+)
+
+// EventType is the kind of change reported by a Watcher.
+type EventType string
+
+const (
+	// EventCreate reports a new file or directory.
+	EventCreate EventType = "create"
+
+	// EventModify reports a content change of a file.
+	EventModify EventType = "modify"
+
+	// EventDelete reports a removed file or directory.
+	EventDelete EventType = "delete"
+
+	// EventRename reports that a path was renamed away; the new name is reported as a create.
+	EventRename EventType = "rename"
+)
+
+// Event is a change to a watched path.
+type Event struct {
+	Path string
+	Type EventType
+}
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	// Recursive watches the whole tree below directory paths instead of their direct children only.
+	Recursive bool
+	// Exclude ignores files and dirs whose name matches any of the patterns, like ListFilesDirs.
+	Exclude []*regexp.Regexp
+	// Debounce is the quiet period used to coalesce bursts; defaults to DefaultWatchDebounce.
+	Debounce time.Duration
+	// Polling forces the polling watcher instead of the OS notifications (e.g. for network filesystems).
+	Polling bool
+	// PollInterval defaults to DefaultWatchPollInterval.
+	PollInterval time.Duration
+}
+
+// watchRoot is a path passed to Watch.
+type watchRoot struct {
+	path string
+	dir  bool
+}
+
+// Watcher delivers debounced filesystem events until its context is cancelled.
+type Watcher struct {
+	opts  WatchOptions
+	roots []watchRoot
+	// present tracks file roots so replacing one by rename is reported as a modify.
+	present map[string]bool
+	events  chan Event
+	errors  chan error
+}
+
+// Watch starts watching the given files or directories. Events and Errors are closed once ctx is done.
+// Events must be drained; Errors may be ignored.
+// OS notifications (inotify on Linux) are used when available, with a polling fallback.
+// The polling watcher cannot detect renames and reports them as a delete and a create.
+func Watch(ctx context.Context, opts WatchOptions, paths ...string) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultWatchDebounce
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultWatchPollInterval
+	}
+
+	w := &Watcher{
+		opts:    opts,
+		present: make(map[string]bool),
+		events:  make(chan Event),
+		errors:  make(chan error, watchErrorBuffer),
+	}
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", p, err)
+		}
+		root := watchRoot{path: filepath.Clean(p), dir: info.IsDir()}
+		w.roots = append(w.roots, root)
+		if !root.dir {
+			w.present[root.path] = true
+		}
+	}
+
+	raw := make(chan Event)
+
+	if !opts.Polling {
+		fsw, err := w.newNotifyWatcher()
+		if err == nil {
+			go w.runNotify(ctx, fsw, raw)
+			go w.debounce(ctx, raw)
+			return w, nil
+		}
+		slog.Warn("Falling back to polling watcher", "error", err)
+	}
+
+	snapshot, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	go w.runPoll(ctx, snapshot, raw)
+	go w.debounce(ctx, raw)
+
+	return w, nil
+}
+
+// Events returns the channel of debounced events.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Errors returns the channel of errors reported while watching.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// included reports whether path belongs to a watched root and is not excluded.
+func (w *Watcher) included(path string) bool {
+	for _, root := range w.roots {
+		if !root.dir {
+			if path == root.path {
+				return true
+			}
+			continue
+		}
+
+		rel, err := filepath.Rel(root.path, path)
+		if err != nil || rel == "." || !filepath.IsLocal(rel) {
+			continue
+		}
+
+		parts := strings.Split(rel, string(filepath.Separator))
+		if !w.opts.Recursive && len(parts) > 1 {
+			continue
+		}
+
+		excluded := false
+		for _, part := range parts {
+			if shouldExclude(part, w.opts.Exclude) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// watchDirs returns the directories to register for OS notifications.
+// File roots are watched through their parent so editors replacing the file by rename keep being tracked.
+func (w *Watcher) watchDirs() ([]string, error) {
+	var dirs []string
+
+	for _, root := range w.roots {
+		if !root.dir {
+			dirs = append(dirs, filepath.Dir(root.path))
+			continue
+		}
+		if !w.opts.Recursive {
+			dirs = append(dirs, root.path)
+			continue
+		}
+
+		subDirs, err := w.walkDirs(root.path)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, subDirs...)
+	}
+
+	return dirs, nil
+}
+
+// walkDirs returns dir and its non-excluded subdirectories.
+func (w *Watcher) walkDirs(dir string) ([]string, error) {
+	var dirs []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("walkdir error at %s: %w", path, err)
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && shouldExclude(d.Name(), w.opts.Exclude) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+
+	return dirs, err
+}
+
+func (w *Watcher) newNotifyWatcher() (*fsnotify.Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := w.watchDirs()
+	if err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		// Adding fails when the inotify watch limit is exhausted, in which case polling takes over.
+		if err := fsw.Add(dir); err != nil {
+			_ = fsw.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	return fsw, nil
+}
+
+// runNotify translates fsnotify events until ctx is done.
+func (w *Watcher) runNotify(ctx context.Context, fsw *fsnotify.Watcher, raw chan<- Event) {
+	defer close(w.errors)
+	defer close(raw)
+	defer func() {
+		_ = fsw.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			if !w.sendError(ctx, err) {
+				return
+			}
+		case ev, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			for _, e := range w.translate(ctx, fsw, ev) {
+				select {
+				case raw <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// translate maps an fsnotify event, registering new directories in recursive mode.
+func (w *Watcher) translate(ctx context.Context, fsw *fsnotify.Watcher, ev fsnotify.Event) []Event {
+	path := filepath.Clean(ev.Name)
+	if !w.included(path) {
+		return nil
+	}
+
+	if existed, tracked := w.present[path]; tracked {
+		switch {
+		case ev.Has(fsnotify.Create):
+			w.present[path] = true
+			if existed {
+				return []Event{{Path: path, Type: EventModify}}
+			}
+		case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+			w.present[path] = false
+		}
+	}
+
+	switch {
+	case ev.Has(fsnotify.Create):
+		events := []Event{{Path: path, Type: EventCreate}}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() || !w.opts.Recursive {
+			return events
+		}
+
+		// Watch the new tree and report entries created before the watch was in place.
+		dirs, err := w.walkDirs(path)
+		if err != nil {
+			w.sendError(ctx, err)
+			return events
+		}
+		for _, dir := range dirs {
+			if err := fsw.Add(dir); err != nil {
+				w.sendError(ctx, fmt.Errorf("failed to watch %s: %w", dir, err))
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				entryPath := filepath.Join(dir, entry.Name())
+				if w.included(entryPath) {
+					events = append(events, Event{Path: entryPath, Type: EventCreate})
+				}
+			}
+		}
+		return events
+	case ev.Has(fsnotify.Remove):
+		return []Event{{Path: path, Type: EventDelete}}
+	case ev.Has(fsnotify.Rename):
+		return []Event{{Path: path, Type: EventRename}}
+	case ev.Has(fsnotify.Write):
+		return []Event{{Path: path, Type: EventModify}}
+	default:
+		// Chmod only changes metadata.
+		return nil
+	}
+}
+
+// sendError reports err without blocking the watch loop; errors are dropped when Errors is not drained.
+func (w *Watcher) sendError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case w.errors <- err:
+	default:
+		slog.Warn("Dropping watcher error", "error", err)
+	}
+	return true
+}
+
+// fileState is the polled state of a path.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+}
+
+// scan records the state of every included path below the roots.
+func (w *Watcher) scan() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+
+	for _, root := range w.roots {
+		if !root.dir {
+			info, err := os.Stat(root.path)
+			if err == nil {
+				snapshot[root.path] = fileState{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+			}
+			continue
+		}
+
+		err := filepath.WalkDir(root.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("walkdir error at %s: %w", path, err)
+			}
+			if path == root.path {
+				return nil
+			}
+			if !w.included(path) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				// Removed while scanning.
+				return nil
+			}
+			snapshot[path] = fileState{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// runPoll rescans the roots every PollInterval and reports the differences until ctx is done.
+func (w *Watcher) runPoll(ctx context.Context, previous map[string]fileState, raw chan<- Event) {
+	defer close(w.errors)
+	defer close(raw)
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := w.scan()
+		if err != nil {
+			if !w.sendError(ctx, err) {
+				return
+			}
+			continue
+		}
+
+		for _, e := range diffSnapshots(previous, current) {
+			select {
+			case raw <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		previous = current
+	}
+}
+
+// diffSnapshots returns the events turning previous into current, sorted by path.
+func diffSnapshots(previous, current map[string]fileState) []Event {
+	var events []Event
+
+	for path, cur := range current {
+		prev, ok := previous[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Path: path, Type: EventCreate})
+		case cur.mode.IsDir():
+			// A directory mtime changes with its children, which are reported on their own.
+		case cur.size != prev.size || !cur.modTime.Equal(prev.modTime) || cur.mode != prev.mode:
+			events = append(events, Event{Path: path, Type: EventModify})
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			events = append(events, Event{Path: path, Type: EventDelete})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
+
+// debounce coalesces raw events per path and delivers them once no event arrived for the debounce period.
+func (w *Watcher) debounce(ctx context.Context, raw <-chan Event) {
+	defer close(w.events)
+
+	pending := make(map[string]Event)
+	var order []string
+
+	timer := time.NewTimer(w.opts.Debounce)
+	timer.Stop()
+
+	flush := func() bool {
+		for _, path := range order {
+			e, ok := pending[path]
+			if !ok {
+				continue
+			}
+			delete(pending, path)
+			select {
+			case w.events <- e:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		pending = make(map[string]Event)
+		order = order[:0]
+		return true
+	}
+
+	for {
+		select {
+		case e, ok := <-raw:
+			if !ok {
+				return
+			}
+			prev, seen := pending[e.Path]
+			if !seen {
+				order = append(order, e.Path)
+			}
+			if merged, keep := mergeEvents(prev, seen, e); keep {
+				pending[e.Path] = merged
+			} else {
+				delete(pending, e.Path)
+			}
+			timer.Reset(w.opts.Debounce)
+		case <-timer.C:
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// mergeEvents folds next into a pending event for the same path; keep is false when they cancel out.
+func mergeEvents(prev Event, seen bool, next Event) (Event, bool) {
+	if !seen {
+		return next, true
+	}
+
+	switch {
+	case prev.Type == EventCreate && next.Type == EventModify:
+		return prev, true
+	case prev.Type == EventCreate && (next.Type == EventDelete || next.Type == EventRename):
+		// A short-lived file (e.g. an editor swap file) never surfaces.
+		return Event{}, false
+	case (prev.Type == EventDelete || prev.Type == EventRename) && next.Type == EventCreate:
+		// Replaced in place, as done by atomic writers.
+		return Event{Path: next.Path, Type: EventModify}, true
+	default:
+		return next, true
+	}
+}
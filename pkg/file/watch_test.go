@@ -0,0 +1,176 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const watchTestTimeout = 5 * time.Second
+
+// collectEvents reads events until no event arrived for quiet.
+func collectEvents(t *testing.T, w *Watcher, quiet time.Duration) []Event {
+	t.Helper()
+
+	var events []Event
+	deadline := time.After(watchTestTimeout)
+	for {
+		select {
+		case e, ok := <-w.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		case <-time.After(quiet):
+			return events
+		case <-deadline:
+			t.Fatal("timed out collecting events")
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	backends := map[string]WatchOptions{
+		"notify":  {Debounce: 50 * time.Millisecond},
+		"polling": {Debounce: 50 * time.Millisecond, Polling: true, PollInterval: 20 * time.Millisecond},
+	}
+
+	for name, base := range backends {
+		t.Run(name, func(t *testing.T) {
+			quiet := 500 * time.Millisecond
+
+			t.Run("create modify delete", func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "a.txt")
+
+				w, err := Watch(t.Context(), base, dir)
+				require.NoError(t, err)
+
+				require.NoError(t, os.WriteFile(path, []byte("a"), 0o644))
+				require.Equal(t, []Event{{Path: path, Type: EventCreate}}, collectEvents(t, w, quiet))
+
+				require.NoError(t, os.WriteFile(path, []byte("changed"), 0o644))
+				require.Equal(t, []Event{{Path: path, Type: EventModify}}, collectEvents(t, w, quiet))
+
+				require.NoError(t, os.Remove(path))
+				require.Equal(t, []Event{{Path: path, Type: EventDelete}}, collectEvents(t, w, quiet))
+			})
+
+			t.Run("exclude", func(t *testing.T) {
+				dir := t.TempDir()
+				opts := base
+				opts.Exclude = []*regexp.Regexp{regexp.MustCompile(`\.swp$`)}
+
+				w, err := Watch(t.Context(), opts, dir)
+				require.NoError(t, err)
+
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "a.swp"), []byte("a"), 0o644))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0o644))
+				require.Equal(t, []Event{{Path: filepath.Join(dir, "b.txt"), Type: EventCreate}}, collectEvents(t, w, quiet))
+			})
+
+			t.Run("recursive", func(t *testing.T) {
+				dir := t.TempDir()
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+				opts := base
+				opts.Recursive = true
+
+				w, err := Watch(t.Context(), opts, dir)
+				require.NoError(t, err)
+
+				nested := filepath.Join(dir, "sub", "nested.txt")
+				require.NoError(t, os.WriteFile(nested, []byte("n"), 0o644))
+				require.Contains(t, collectEvents(t, w, quiet), Event{Path: nested, Type: EventCreate})
+
+				newDir := filepath.Join(dir, "new")
+				require.NoError(t, os.Mkdir(newDir, 0o755))
+				require.Contains(t, collectEvents(t, w, quiet), Event{Path: newDir, Type: EventCreate})
+
+				inNew := filepath.Join(newDir, "file.txt")
+				require.NoError(t, os.WriteFile(inNew, []byte("f"), 0o644))
+				require.Contains(t, collectEvents(t, w, quiet), Event{Path: inNew, Type: EventCreate})
+			})
+
+			t.Run("not recursive", func(t *testing.T) {
+				dir := t.TempDir()
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+
+				w, err := Watch(t.Context(), base, dir)
+				require.NoError(t, err)
+
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "nested.txt"), []byte("n"), 0o644))
+				for _, e := range collectEvents(t, w, quiet) {
+					require.NotEqual(t, filepath.Join(dir, "sub", "nested.txt"), e.Path)
+				}
+			})
+
+			t.Run("single file", func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "config.env")
+				require.NoError(t, os.WriteFile(path, []byte("A=1"), 0o644))
+
+				w, err := Watch(t.Context(), base, path)
+				require.NoError(t, err)
+
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), []byte("x"), 0o644))
+				require.NoError(t, WriteFileAtomic(path, []byte("A=2"), AtomicWriteOptions{}))
+				require.Equal(t, []Event{{Path: path, Type: EventModify}}, collectEvents(t, w, quiet))
+			})
+
+			t.Run("context cancel closes channels", func(t *testing.T) {
+				ctx, cancel := context.WithCancel(t.Context())
+				w, err := Watch(ctx, base, t.TempDir())
+				require.NoError(t, err)
+
+				cancel()
+				select {
+				case _, ok := <-w.Events():
+					require.False(t, ok)
+				case <-time.After(watchTestTimeout):
+					t.Fatal("events channel not closed")
+				}
+				select {
+				case _, ok := <-w.Errors():
+					require.False(t, ok)
+				case <-time.After(watchTestTimeout):
+					t.Fatal("errors channel not closed")
+				}
+			})
+		})
+	}
+
+	t.Run("missing path", func(t *testing.T) {
+		_, err := Watch(t.Context(), WatchOptions{}, filepath.Join(t.TempDir(), "missing"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestMergeEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     EventType
+		next     EventType
+		expected EventType
+		keep     bool
+	}{
+		{name: "create then modify", prev: EventCreate, next: EventModify, expected: EventCreate, keep: true},
+		{name: "create then delete", prev: EventCreate, next: EventDelete, keep: false},
+		{name: "delete then create", prev: EventDelete, next: EventCreate, expected: EventModify, keep: true},
+		{name: "rename then create", prev: EventRename, next: EventCreate, expected: EventModify, keep: true},
+		{name: "modify then delete", prev: EventModify, next: EventDelete, expected: EventDelete, keep: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, keep := mergeEvents(Event{Path: "a", Type: tt.prev}, true, Event{Path: "a", Type: tt.next})
+			require.Equal(t, tt.keep, keep)
+			if keep {
+				require.Equal(t, tt.expected, merged.Type)
+			}
+		})
+	}
+}
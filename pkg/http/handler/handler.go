@@ -7,7 +7,7 @@ import (
 	commonHttp "github.com/hibare/GoCommon/v2/pkg/http"
 )
 
-// HealthCheck is a simple health check handler.
+// HealthCheck is a simple health check handler. Use HealthRegistry to report the health of dependencies.
 func HealthCheck(w http.ResponseWriter, _ *http.Request) {
 	commonHttp.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
 }
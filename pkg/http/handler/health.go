@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	commonHttp "github.com/hibare/GoCommon/v2/pkg/http"
+	"github.com/hibare/GoCommon/v2/pkg/http/client"
+)
+
+const (
+	// DefaultHealthCheckTimeout bounds the duration of a check.
+	DefaultHealthCheckTimeout = 5 * time.Second
+
+	// DefaultHealthCheckCacheTTL is the duration a check result is reused for.
+	DefaultHealthCheckCacheTTL = 5 * time.Second
+)
+
+// HealthStatus is the state of a check or of a whole report.
+type HealthStatus string
+
+const (
+	// HealthStatusUp reports that everything works.
+	HealthStatusUp HealthStatus = "up"
+
+	// HealthStatusDegraded reports that a non-critical check failed; the service still serves requests.
+	HealthStatusDegraded HealthStatus = "degraded"
+
+	// HealthStatusDown reports that a critical check failed.
+	HealthStatusDown HealthStatus = "down"
+)
+
+var (
+	// ErrHealthCheckExists is returned when registering a check under a name already in use.
+	ErrHealthCheckExists = errors.New("health check already registered")
+
+	// ErrHealthDegraded is wrapped by check errors to report a dependency that works in a degraded way,
+	// e.g. with high latency. The check is then degraded instead of down, even when it is critical.
+	ErrHealthDegraded = errors.New("degraded")
+)
+
+// HealthCheckFunc checks a dependency, e.g. pings a database, and returns an error when it is unhealthy.
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheckOptions configures a check registered with HealthRegistry.Register.
+type HealthCheckOptions struct {
+	// Timeout bounds the check; defaults to DefaultHealthCheckTimeout.
+	Timeout time.Duration
+	// CacheTTL is the duration a result is reused for; defaults to DefaultHealthCheckCacheTTL, negative disables caching.
+	CacheTTL time.Duration
+	// Critical checks make the service down when they fail; other checks make it degraded.
+	Critical bool
+	// Liveness includes the check in the liveness report. Checks are only part of the readiness report by default,
+	// since a failing dependency should not get the process restarted.
+	Liveness bool
+}
+
+// HealthCheckResult is the outcome of a check.
+type HealthCheckResult struct {
+	Status    HealthStatus `json:"status"`
+	Critical  bool         `json:"critical"`
+	Error     string       `json:"error,omitempty"`
+	Duration  string       `json:"duration"`
+	CheckedAt time.Time    `json:"checkedAt"`
+}
+
+// HealthReport is the outcome of the checks of a liveness or readiness probe.
+type HealthReport struct {
+	Status HealthStatus                 `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// healthCheck is a registered check with its cached result.
+type healthCheck struct {
+	check HealthCheckFunc
+	opts  HealthCheckOptions
+
+	// mu makes concurrent probes share a single run of the check.
+	mu     sync.Mutex
+	result HealthCheckResult
+}
+
+// HealthRegistry holds named checks and exposes them as liveness and readiness probes.
+type HealthRegistry struct {
+	mu     sync.RWMutex
+	checks map[string]*healthCheck
+}
+
+// NewHealthRegistry returns an empty registry. Its probes report up until checks are registered.
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{checks: make(map[string]*healthCheck)}
+}
+
+// Register adds a named check.
+func (r *HealthRegistry) Register(name string, check HealthCheckFunc, opts HealthCheckOptions) error {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHealthCheckTimeout
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = DefaultHealthCheckCacheTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.checks[name]; ok {
+		return fmt.Errorf("%w: %s", ErrHealthCheckExists, name)
+	}
+	r.checks[name] = &healthCheck{check: check, opts: opts}
+	return nil
+}
+
+// Liveness runs the checks registered with the Liveness option.
+func (r *HealthRegistry) Liveness(ctx context.Context) HealthReport {
+	return r.report(ctx, true)
+}
+
+// Readiness runs all the checks.
+func (r *HealthRegistry) Readiness(ctx context.Context) HealthReport {
+	return r.report(ctx, false)
+}
+
+// LivenessHandler serves the liveness report, with status 503 when it is down.
+func (r *HealthRegistry) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		writeHealthReport(w, r.Liveness(req.Context()))
+	}
+}
+
+// ReadinessHandler serves the readiness report, with status 503 when it is down.
+func (r *HealthRegistry) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		writeHealthReport(w, r.Readiness(req.Context()))
+	}
+}
+
+// URLHealthCheck returns a check requesting url with a GET, which fails unless the response status is 2xx or 3xx.
+func URLHealthCheck(c client.ClientIface, url string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to request %s: %w", url, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+		}
+		return nil
+	}
+}
+
+func (r *HealthRegistry) report(ctx context.Context, liveness bool) HealthReport {
+	r.mu.RLock()
+	checks := make(map[string]*healthCheck, len(r.checks))
+	for name, c := range r.checks {
+		if !liveness || c.opts.Liveness {
+			checks[name] = c
+		}
+	}
+	r.mu.RUnlock()
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]HealthCheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = checks[name].run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthStatusUp}
+	if len(names) > 0 {
+		report.Checks = make(map[string]HealthCheckResult, len(names))
+	}
+	for i, name := range names {
+		result := results[i]
+		report.Checks[name] = result
+
+		switch {
+		case result.Status == HealthStatusDown && result.Critical:
+			report.Status = HealthStatusDown
+		case result.Status != HealthStatusUp && report.Status == HealthStatusUp:
+			report.Status = HealthStatusDegraded
+		}
+	}
+	return report
+}
+
+// run returns the cached result, or runs the check when it expired.
+func (c *healthCheck) run(ctx context.Context) HealthCheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.opts.CacheTTL > 0 && !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.opts.CacheTTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	// The check runs in its own goroutine so the timeout holds even when it ignores ctx.
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("health check panicked: %v", p)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check timed out: %w", ctx.Err())
+	}
+
+	result := HealthCheckResult{
+		Status:    HealthStatusUp,
+		Critical:  c.opts.Critical,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	switch {
+	case errors.Is(err, ErrHealthDegraded):
+		result.Status = HealthStatusDegraded
+	case err != nil:
+		result.Status = HealthStatusDown
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	// A probe canceled by its caller says nothing about the dependency.
+	if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		c.result = result
+	}
+	return result
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	statusCode := http.StatusOK
+	if report.Status == HealthStatusDown {
+		statusCode = http.StatusServiceUnavailable
+	}
+	commonHttp.WriteJSONResponse(w, statusCode, report)
+}
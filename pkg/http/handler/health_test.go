@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errUnhealthy = errors.New("connection refused")
+
+func healthyCheck(context.Context) error {
+	return nil
+}
+
+func failingCheck(context.Context) error {
+	return errUnhealthy
+}
+
+func TestHealthRegistry_Status(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		Check       HealthCheckFunc
+		Critical    bool
+		Expected    HealthStatus
+		CheckStatus HealthStatus
+	}{
+		{Name: "healthy", Check: healthyCheck, Critical: true, Expected: HealthStatusUp, CheckStatus: HealthStatusUp},
+		{Name: "critical failure", Check: failingCheck, Critical: true, Expected: HealthStatusDown, CheckStatus: HealthStatusDown},
+		{Name: "non-critical failure", Check: failingCheck, Expected: HealthStatusDegraded, CheckStatus: HealthStatusDown},
+		{
+			Name: "degraded critical",
+			Check: func(context.Context) error {
+				return fmt.Errorf("slow replies: %w", ErrHealthDegraded)
+			},
+			Critical:    true,
+			Expected:    HealthStatusDegraded,
+			CheckStatus: HealthStatusDegraded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			registry := NewHealthRegistry()
+			require.NoError(t, registry.Register("ok", healthyCheck, HealthCheckOptions{Critical: true}))
+			require.NoError(t, registry.Register("dep", tc.Check, HealthCheckOptions{Critical: tc.Critical}))
+
+			report := registry.Readiness(t.Context())
+			require.Equal(t, tc.Expected, report.Status)
+			require.Equal(t, HealthStatusUp, report.Checks["ok"].Status)
+			require.Equal(t, tc.CheckStatus, report.Checks["dep"].Status)
+			require.Equal(t, tc.Critical, report.Checks["dep"].Critical)
+		})
+	}
+}
+
+func TestHealthRegistry_Register(t *testing.T) {
+	registry := NewHealthRegistry()
+	require.NoError(t, registry.Register("db", healthyCheck, HealthCheckOptions{}))
+	require.ErrorIs(t, registry.Register("db", healthyCheck, HealthCheckOptions{}), ErrHealthCheckExists)
+}
+
+func TestHealthRegistry_Liveness(t *testing.T) {
+	registry := NewHealthRegistry()
+	require.Equal(t, HealthReport{Status: HealthStatusUp}, registry.Liveness(t.Context()))
+
+	require.NoError(t, registry.Register("db", failingCheck, HealthCheckOptions{Critical: true}))
+	require.NoError(t, registry.Register("deadlock", healthyCheck, HealthCheckOptions{Critical: true, Liveness: true}))
+
+	report := registry.Liveness(t.Context())
+	require.Equal(t, HealthStatusUp, report.Status)
+	require.Len(t, report.Checks, 1)
+	require.Contains(t, report.Checks, "deadlock")
+
+	require.Equal(t, HealthStatusDown, registry.Readiness(t.Context()).Status)
+}
+
+func TestHealthRegistry_Timeout(t *testing.T) {
+	registry := NewHealthRegistry()
+	block := make(chan struct{})
+	defer close(block)
+
+	// The check ignores its context.
+	require.NoError(t, registry.Register("stuck", func(context.Context) error {
+		<-block
+		return nil
+	}, HealthCheckOptions{Critical: true, Timeout: 20 * time.Millisecond}))
+
+	report := registry.Readiness(t.Context())
+	require.Equal(t, HealthStatusDown, report.Status)
+	require.Contains(t, report.Checks["stuck"].Error, "timed out")
+}
+
+func TestHealthRegistry_Panic(t *testing.T) {
+	registry := NewHealthRegistry()
+	require.NoError(t, registry.Register("buggy", func(context.Context) error {
+		panic("boom")
+	}, HealthCheckOptions{Critical: true}))
+
+	report := registry.Readiness(t.Context())
+	require.Equal(t, HealthStatusDown, report.Status)
+	require.Contains(t, report.Checks["buggy"].Error, "boom")
+}
+
+func TestHealthRegistry_Cache(t *testing.T) {
+	countingCheck := func(calls *atomic.Int32) HealthCheckFunc {
+		return func(context.Context) error {
+			calls.Add(1)
+			return nil
+		}
+	}
+
+	t.Run("results are reused", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := NewHealthRegistry()
+		require.NoError(t, registry.Register("db", countingCheck(&calls), HealthCheckOptions{CacheTTL: 50 * time.Millisecond}))
+
+		registry.Readiness(t.Context())
+		registry.Readiness(t.Context())
+		require.Equal(t, int32(1), calls.Load())
+
+		time.Sleep(60 * time.Millisecond)
+		registry.Readiness(t.Context())
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("negative TTL disables caching", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := NewHealthRegistry()
+		require.NoError(t, registry.Register("db", countingCheck(&calls), HealthCheckOptions{CacheTTL: -1}))
+
+		registry.Readiness(t.Context())
+		registry.Readiness(t.Context())
+		require.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestHealthRegistry_Handlers(t *testing.T) {
+	registry := NewHealthRegistry()
+	require.NoError(t, registry.Register("db", failingCheck, HealthCheckOptions{Critical: true}))
+
+	testCases := []struct {
+		Name       string
+		Handler    http.HandlerFunc
+		StatusCode int
+		Status     HealthStatus
+	}{
+		{Name: "liveness", Handler: registry.LivenessHandler(), StatusCode: http.StatusOK, Status: HealthStatusUp},
+		{Name: "readiness", Handler: registry.ReadinessHandler(), StatusCode: http.StatusServiceUnavailable, Status: HealthStatusDown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "/health", nil)
+			require.NoError(t, err)
+
+			tc.Handler(w, r)
+
+			require.Equal(t, tc.StatusCode, w.Code)
+			require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var report HealthReport
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+			require.Equal(t, tc.Status, report.Status)
+		})
+	}
+}
+
+func TestURLHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, URLHealthCheck(server.Client(), server.URL+"/up")(t.Context()))
+	require.ErrorContains(t, URLHealthCheck(server.Client(), server.URL+"/down")(t.Context()), "unexpected status code 502")
+}
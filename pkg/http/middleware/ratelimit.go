@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hibare/GoCommon/v2/pkg/errors"
+	commonHttp "github.com/hibare/GoCommon/v2/pkg/http"
+)
+
+const (
+	// DefaultRateLimit is the number of requests allowed per window.
+	DefaultRateLimit = 60
+
+	// DefaultRateLimitWindow is the window of DefaultRateLimit.
+	DefaultRateLimitWindow = time.Minute
+)
+
+// RateLimitAlgorithm selects how requests are counted.
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitTokenBucket refills Limit tokens evenly over Window and allows bursts of up to Limit requests.
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+
+	// RateLimitSlidingWindow allows Limit requests over any Window, estimated from the counts
+	// of the current and previous fixed windows.
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimitRule is the limit applied to each key.
+type RateLimitRule struct {
+	Limit     int
+	Window    time.Duration
+	Algorithm RateLimitAlgorithm
+}
+
+// RateLimitResult is the outcome of counting a request.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the duration until the limit is fully available again.
+	Reset time.Duration
+	// RetryAfter is the duration until the next request is allowed, zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore counts the requests of each key, e.g. in memory or in a store shared by several instances.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns the key a request is counted under.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitOptions configures RateLimit.
+type RateLimitOptions struct {
+	// Limit and Window default to DefaultRateLimit and DefaultRateLimitWindow.
+	Limit  int
+	Window time.Duration
+	// Algorithm defaults to RateLimitTokenBucket.
+	Algorithm RateLimitAlgorithm
+	// KeyFunc defaults to KeyByIP.
+	KeyFunc RateLimitKeyFunc
+	// Store defaults to a MemoryRateLimitStore with default options.
+	Store RateLimitStore
+}
+
+// RateLimit is a middleware that limits the requests of each key, answering 429 once the limit is exceeded.
+// It sets the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers,
+// and Retry-After on rejected requests. Requests are allowed when the store fails.
+func RateLimit(next http.Handler, opts RateLimitOptions) http.Handler {
+	rule := RateLimitRule{Limit: opts.Limit, Window: opts.Window, Algorithm: opts.Algorithm}
+	if rule.Limit <= 0 {
+		rule.Limit = DefaultRateLimit
+	}
+	if rule.Window <= 0 {
+		rule.Window = DefaultRateLimitWindow
+	}
+	if rule.Algorithm == "" {
+		rule.Algorithm = RateLimitTokenBucket
+	}
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore(MemoryRateLimitStoreOptions{})
+	}
+	policy := fmt.Sprintf("%d;w=%d", rule.Limit, ceilSeconds(rule.Window))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := store.Take(r.Context(), keyFunc(r), rule)
+		if err != nil {
+			slog.Error("Failed to apply rate limit", "path", r.URL.Path, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(rule.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+		w.Header().Set("RateLimit-Policy", policy)
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			commonHttp.WriteErrorResponse(w, http.StatusTooManyRequests, errors.ErrTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// KeyByIP keys requests by the IP address of the client connection. Behind a reverse proxy, the proxy must
+// rewrite the remote address of requests, since forwarding headers can be forged by clients.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// KeyByToken keys requests by their API token from the AuthHeaderName header, hashed so stores never hold it.
+// Requests without a token are keyed by IP. The token is not checked, so RateLimit must be wrapped by
+// TokenAuth; otherwise a client gets a fresh limit with every made-up token. See KeyByValidToken.
+func KeyByToken(r *http.Request) string {
+	token := r.Header.Get(AuthHeaderName)
+	if token == "" {
+		return KeyByIP(r)
+	}
+	return tokenKey(token)
+}
+
+// KeyByValidToken is KeyByToken for rate limits applied before authentication: requests whose token
+// valid rejects are keyed by IP.
+func KeyByValidToken(valid func(token string) bool) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		token := r.Header.Get(AuthHeaderName)
+		if token == "" || !valid(token) {
+			return KeyByIP(r)
+		}
+		return tokenKey(token)
+	}
+}
+
+// tokenKey returns the key of an API token, hashed so stores never hold it.
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:])
+}
+
+// ceilSeconds rounds d up to whole seconds, as expected by the rate limit headers.
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimitMaxKeys is the number of keys kept by a MemoryRateLimitStore.
+const DefaultRateLimitMaxKeys = 100_000
+
+// ErrUnknownRateLimitAlgorithm is returned by stores for an unsupported RateLimitAlgorithm.
+var ErrUnknownRateLimitAlgorithm = errors.New("unknown rate limit algorithm")
+
+// MemoryRateLimitStoreOptions configures NewMemoryRateLimitStore.
+type MemoryRateLimitStoreOptions struct {
+	// MaxKeys bounds memory use; defaults to DefaultRateLimitMaxKeys.
+	// The least recently seen key is evicted when it is exceeded.
+	MaxKeys int
+}
+
+// MemoryRateLimitStore is a RateLimitStore keeping counters in memory, for a single instance.
+// Keys whose limit is fully available again are evicted as requests come in.
+type MemoryRateLimitStore struct {
+	maxKeys int
+	now     func() time.Time
+
+	mu sync.Mutex
+	// lru orders the entries from the most to the least recently seen.
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// rateLimitEntry holds the state of a key for both algorithms.
+type rateLimitEntry struct {
+	key string
+	// expires is when the entry is equivalent to a new one and can be evicted.
+	expires time.Time
+
+	// Token bucket state.
+	tokens float64
+	last   time.Time
+
+	// Sliding window state.
+	windowStart time.Time
+	current     int
+	previous    int
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore(opts MemoryRateLimitStoreOptions) *MemoryRateLimitStore {
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = DefaultRateLimitMaxKeys
+	}
+
+	return &MemoryRateLimitStore{
+		maxKeys: opts.MaxKeys,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Take counts a request of key.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	if rule.Algorithm != RateLimitTokenBucket && rule.Algorithm != RateLimitSlidingWindow {
+		return RateLimitResult{}, fmt.Errorf("%w: %q", ErrUnknownRateLimitAlgorithm, rule.Algorithm)
+	}
+
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired(now)
+
+	element, ok := s.entries[key]
+	if ok {
+		s.lru.MoveToFront(element)
+	} else {
+		element = s.lru.PushFront(&rateLimitEntry{key: key})
+		s.entries[key] = element
+		if s.lru.Len() > s.maxKeys {
+			s.remove(s.lru.Back())
+		}
+	}
+
+	entry := element.Value.(*rateLimitEntry) //nolint:errcheck // reason: the list only holds entries
+	if rule.Algorithm == RateLimitSlidingWindow {
+		return entry.takeSlidingWindow(now, rule), nil
+	}
+	return entry.takeTokenBucket(now, rule), nil
+}
+
+// Len returns the number of keys held.
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// evictExpired removes the least recently seen entries while they are expired. An entry can still expire
+// before more recently seen ones when its window is shorter; it is then evicted once it reaches the back.
+func (s *MemoryRateLimitStore) evictExpired(now time.Time) {
+	for element := s.lru.Back(); element != nil; element = s.lru.Back() {
+		if element.Value.(*rateLimitEntry).expires.After(now) { //nolint:errcheck // reason: the list only holds entries
+			return
+		}
+		s.remove(element)
+	}
+}
+
+func (s *MemoryRateLimitStore) remove(element *list.Element) {
+	entry := s.lru.Remove(element).(*rateLimitEntry) //nolint:errcheck // reason: the list only holds entries
+	delete(s.entries, entry.key)
+}
+
+// takeTokenBucket refills the bucket for the time elapsed since the last request and takes a token.
+func (e *rateLimitEntry) takeTokenBucket(now time.Time, rule RateLimitRule) RateLimitResult {
+	limit := float64(rule.Limit)
+	perToken := rule.Window / time.Duration(rule.Limit)
+
+	if e.last.IsZero() {
+		e.tokens = limit
+	} else {
+		e.tokens = math.Min(limit, e.tokens+float64(now.Sub(e.last))/float64(perToken))
+	}
+	e.last = now
+
+	result := RateLimitResult{Allowed: e.tokens >= 1}
+	if result.Allowed {
+		e.tokens--
+	} else {
+		result.RetryAfter = time.Duration((1 - e.tokens) * float64(perToken))
+	}
+	result.Remaining = int(e.tokens)
+	result.Reset = time.Duration((limit - e.tokens) * float64(perToken))
+	e.expires = now.Add(result.Reset)
+	return result
+}
+
+// takeSlidingWindow counts the request in the current window if the estimated count over the last
+// window, weighting the previous window by its overlap, stays within the limit.
+func (e *rateLimitEntry) takeSlidingWindow(now time.Time, rule RateLimitRule) RateLimitResult {
+	start := now.Truncate(rule.Window)
+	switch {
+	case start.Equal(e.windowStart):
+	case start.Equal(e.windowStart.Add(rule.Window)):
+		e.previous, e.current = e.current, 0
+	default:
+		e.previous, e.current = 0, 0
+	}
+	e.windowStart = start
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(rule.Window)
+	estimate := func() float64 {
+		return float64(e.previous)*weight + float64(e.current)
+	}
+
+	result := RateLimitResult{Allowed: estimate()+1 <= float64(rule.Limit)}
+	if result.Allowed {
+		e.current++
+	} else {
+		result.RetryAfter = e.retryAfter(elapsed, rule)
+	}
+	result.Remaining = max(0, rule.Limit-int(math.Ceil(estimate())))
+
+	// The current count stops weighing once the next window is over.
+	end := start.Add(2 * rule.Window)
+	if e.current == 0 {
+		end = start.Add(rule.Window)
+	}
+	result.Reset = end.Sub(now)
+	e.expires = end
+	return result
+}
+
+// retryAfter returns the time until the estimated count leaves room for one more request.
+func (e *rateLimitEntry) retryAfter(elapsed time.Duration, rule RateLimitRule) time.Duration {
+	untilNextWindow := rule.Window - elapsed
+	if room := rule.Limit - e.current - 1; room >= 0 && e.previous > 0 {
+		// Solve previous * (1 - (elapsed + wait) / window) <= room.
+		wait := time.Duration((1-float64(room)/float64(e.previous))*float64(rule.Window)) - elapsed
+		return min(max(wait, 0), untilNextWindow)
+	}
+	// The current count becomes the previous one in the next window and must weigh little enough.
+	wait := time.Duration((1 - float64(rule.Limit-1)/float64(e.current)) * float64(rule.Window))
+	return untilNextWindow + max(wait, 0)
+}
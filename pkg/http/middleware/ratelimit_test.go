@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	commonErrors "github.com/hibare/GoCommon/v2/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable time source for MemoryRateLimitStore.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestStore(opts MemoryRateLimitStoreOptions) (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryRateLimitStore(opts)
+	store.now = clock.Now
+	return store, clock
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, RateLimitRule) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func serveRateLimited(handler http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = remoteAddr
+	if token != "" {
+		request.Header.Set(AuthHeaderName, token)
+	}
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+func TestRateLimit(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("limits per client IP", func(t *testing.T) {
+		store, _ := newTestStore(MemoryRateLimitStoreOptions{})
+		mw := RateLimit(okHandler, RateLimitOptions{Limit: 2, Window: time.Minute, Store: store})
+
+		response := serveRateLimited(mw, "192.0.2.1:1234", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "1", response.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "30", response.Header().Get("RateLimit-Reset"))
+		require.Equal(t, "2;w=60", response.Header().Get("RateLimit-Policy"))
+
+		require.Equal(t, http.StatusOK, serveRateLimited(mw, "192.0.2.1:5678", "").Code)
+
+		response = serveRateLimited(mw, "192.0.2.1:1234", "")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		require.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "30", response.Header().Get("Retry-After"))
+
+		var body commonErrors.Error
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		require.Equal(t, commonErrors.Error{Code: http.StatusTooManyRequests, Message: commonErrors.ErrTooManyRequests.Error()}, body)
+
+		require.Equal(t, http.StatusOK, serveRateLimited(mw, "192.0.2.2:1234", "").Code)
+	})
+
+	t.Run("limits per token", func(t *testing.T) {
+		store, _ := newTestStore(MemoryRateLimitStoreOptions{})
+		mw := RateLimit(okHandler, RateLimitOptions{Limit: 1, KeyFunc: KeyByToken, Store: store})
+
+		require.Equal(t, http.StatusOK, serveRateLimited(mw, "192.0.2.1:1234", "token-a").Code)
+		require.Equal(t, http.StatusTooManyRequests, serveRateLimited(mw, "192.0.2.2:1234", "token-a").Code)
+		require.Equal(t, http.StatusOK, serveRateLimited(mw, "192.0.2.1:1234", "token-b").Code)
+	})
+
+	t.Run("custom key", func(t *testing.T) {
+		mw := RateLimit(okHandler, RateLimitOptions{
+			Limit:   1,
+			KeyFunc: func(*http.Request) string { return "global" },
+		})
+
+		require.Equal(t, http.StatusOK, serveRateLimited(mw, "192.0.2.1:1234", "").Code)
+		require.Equal(t, http.StatusTooManyRequests, serveRateLimited(mw, "192.0.2.2:1234", "").Code)
+	})
+
+	t.Run("store errors allow requests", func(t *testing.T) {
+		mw := RateLimit(okHandler, RateLimitOptions{Limit: 1, Store: failingStore{}})
+
+		response := serveRateLimited(mw, "192.0.2.1:1234", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Empty(t, response.Header().Get("RateLimit-Limit"))
+	})
+}
+
+func TestKeyFuncs(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "[2001:db8::1]:443"
+	require.Equal(t, "ip:2001:db8::1", KeyByIP(request))
+	require.Equal(t, "ip:2001:db8::1", KeyByToken(request))
+
+	request.Header.Set(AuthHeaderName, testToken)
+	key := KeyByToken(request)
+	require.Regexp(t, "^token:[0-9a-f]{64}$", key)
+	require.NotContains(t, key, testToken)
+
+	t.Run("valid tokens", func(t *testing.T) {
+		keyFunc := KeyByValidToken(func(token string) bool {
+			return token == testToken
+		})
+		require.Equal(t, key, keyFunc(request))
+
+		request.Header.Set(AuthHeaderName, "made-up")
+		require.Equal(t, "ip:2001:db8::1", keyFunc(request))
+
+		request.Header.Del(AuthHeaderName)
+		require.Equal(t, "ip:2001:db8::1", keyFunc(request))
+	})
+}
+
+func TestMemoryRateLimitStore_TokenBucket(t *testing.T) {
+	ctx := t.Context()
+	store, clock := newTestStore(MemoryRateLimitStoreOptions{})
+	rule := RateLimitRule{Limit: 3, Window: 3 * time.Second, Algorithm: RateLimitTokenBucket}
+
+	// A full bucket allows a burst.
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "key", rule)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "key", rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.Reset)
+
+	// Tokens are refilled one per second.
+	clock.Advance(time.Second)
+	result, err = store.Take(ctx, "key", rule)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+}
+
+func TestMemoryRateLimitStore_SlidingWindow(t *testing.T) {
+	ctx := t.Context()
+	store, clock := newTestStore(MemoryRateLimitStoreOptions{})
+	rule := RateLimitRule{Limit: 4, Window: 10 * time.Second, Algorithm: RateLimitSlidingWindow}
+
+	for range 4 {
+		result, err := store.Take(ctx, "key", rule)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := store.Take(ctx, "key", rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	// The 4 requests must weigh at most 3 in the next window, a quarter into it.
+	require.Equal(t, 12500*time.Millisecond, result.RetryAfter)
+
+	// Half into the next window the previous requests weigh 2.
+	clock.Advance(15 * time.Second)
+	for range 2 {
+		result, err = store.Take(ctx, "key", rule)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	result, err = store.Take(ctx, "key", rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	// Both windows are over.
+	clock.Advance(20 * time.Second)
+	result, err = store.Take(ctx, "key", rule)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 3, result.Remaining)
+}
+
+func TestMemoryRateLimitStore_Eviction(t *testing.T) {
+	ctx := t.Context()
+	rule := RateLimitRule{Limit: 1, Window: time.Minute, Algorithm: RateLimitTokenBucket}
+
+	t.Run("expired keys", func(t *testing.T) {
+		store, clock := newTestStore(MemoryRateLimitStoreOptions{})
+		_, err := store.Take(ctx, "a", rule)
+		require.NoError(t, err)
+		_, err = store.Take(ctx, "b", rule)
+		require.NoError(t, err)
+		require.Equal(t, 2, store.Len())
+
+		clock.Advance(time.Minute)
+		_, err = store.Take(ctx, "c", rule)
+		require.NoError(t, err)
+		require.Equal(t, 1, store.Len())
+	})
+
+	t.Run("least recently seen key over MaxKeys", func(t *testing.T) {
+		store, _ := newTestStore(MemoryRateLimitStoreOptions{MaxKeys: 2})
+		for _, key := range []string{"a", "b", "a", "c"} {
+			_, err := store.Take(ctx, key, rule)
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2, store.Len())
+
+		// "a" is still limited while "b" was evicted, so it gets a full limit again.
+		result, err := store.Take(ctx, "a", rule)
+		require.NoError(t, err)
+		require.False(t, result.Allowed)
+		result, err = store.Take(ctx, "b", rule)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	})
+}
+
+func TestMemoryRateLimitStore_UnknownAlgorithm(t *testing.T) {
+	store := NewMemoryRateLimitStore(MemoryRateLimitStoreOptions{})
+	_, err := store.Take(t.Context(), "key", RateLimitRule{Limit: 1, Window: time.Second, Algorithm: "leaky"})
+	require.ErrorIs(t, err, ErrUnknownRateLimitAlgorithm)
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrServerTLSConfig is returned when only one of the TLS certificate and key files is set.
+var ErrServerTLSConfig = errors.New("both TLS certificate and key files are required")
+
+// ShutdownHook releases a resource, e.g. closes a database or flushes buffers, when the server stops.
+type ShutdownHook func(ctx context.Context) error
+
+// ServerOptions configures NewServer. Zero durations default to the DefaultServer* constants.
+type ServerOptions struct {
+	// Addr is the TCP address to listen on, e.g. ":8080". It is ignored when UnixSocket is set.
+	Addr string
+	// UnixSocket is the path of a Unix domain socket to listen on instead of Addr.
+	// A stale socket left at this path is removed, and the socket is removed when the server stops.
+	UnixSocket string
+
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownGracePeriod bounds the time spent draining connections, then the time spent running hooks.
+	ShutdownGracePeriod time.Duration
+	// MaxHeaderBytes defaults to DefaultHTTPRequestSize.
+	MaxHeaderBytes int
+
+	// TLSCertFile and TLSKeyFile enable TLS with the given PEM files.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSConfig enables TLS with its certificates, or customizes the one of TLSCertFile and TLSKeyFile.
+	TLSConfig *tls.Config
+
+	// Signals stop the server; defaults to SIGINT and SIGTERM.
+	Signals []os.Signal
+}
+
+// namedHook is a shutdown hook registered with OnShutdown.
+type namedHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// Server runs an http.Server until its context is done or a stop signal is received,
+// then drains connections and runs the shutdown hooks.
+type Server struct {
+	opts   ServerOptions
+	server *http.Server
+
+	mu       sync.Mutex
+	listener net.Listener
+	hooks    []namedHook
+}
+
+// NewServer returns a Server serving handler with opts.
+func NewServer(handler http.Handler, opts ServerOptions) (*Server, error) {
+	if (opts.TLSCertFile == "") != (opts.TLSKeyFile == "") {
+		return nil, ErrServerTLSConfig
+	}
+
+	if opts.ReadTimeout <= 0 {
+		opts.ReadTimeout = DefaultServerReadTimeout
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultServerWriteTimeout
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultServerIdleTimeout
+	}
+	if opts.ShutdownGracePeriod <= 0 {
+		opts.ShutdownGracePeriod = DefaultServerShutdownGracePeriod
+	}
+	if opts.MaxHeaderBytes <= 0 {
+		opts.MaxHeaderBytes = DefaultHTTPRequestSize
+	}
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	return &Server{
+		opts: opts,
+		server: &http.Server{
+			Handler:           handler,
+			ReadTimeout:       opts.ReadTimeout,
+			ReadHeaderTimeout: opts.ReadTimeout,
+			WriteTimeout:      opts.WriteTimeout,
+			IdleTimeout:       opts.IdleTimeout,
+			MaxHeaderBytes:    opts.MaxHeaderBytes,
+			TLSConfig:         opts.TLSConfig,
+		},
+	}, nil
+}
+
+// OnShutdown registers a hook run after connections are drained. Hooks run in reverse order of registration,
+// like deferred calls, and all of them run even when one fails.
+func (s *Server) OnShutdown(name string, hook ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, namedHook{name: name, hook: hook})
+}
+
+// Listen opens the listener, so Addr is known before Run, e.g. when listening on port 0.
+// Run calls it when it was not called.
+func (s *Server) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return nil
+	}
+
+	network, address := "tcp", s.opts.Addr
+	if s.opts.UnixSocket != "" {
+		network, address = "unix", s.opts.UnixSocket
+		if err := removeStaleSocket(address); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Addr returns the address the server listens on, nil before Listen or Run.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Run serves until ctx is done or one of the stop signals is received, then shuts the server down
+// gracefully within the grace period and runs the shutdown hooks. It returns nil after a requested stop.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return errors.Join(err, s.runHooks(ctx))
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, s.opts.Signals...)
+	defer stop()
+
+	tlsEnabled := s.opts.TLSCertFile != "" || s.opts.TLSConfig != nil
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsEnabled {
+			serveErr <- s.server.ServeTLS(s.listener, s.opts.TLSCertFile, s.opts.TLSKeyFile)
+			return
+		}
+		serveErr <- s.server.Serve(s.listener)
+	}()
+	slog.Info("Starting HTTP server", "address", s.listener.Addr().String(), "tls", tlsEnabled)
+
+	var err error
+	select {
+	case err = <-serveErr:
+		err = fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+		slog.Info("Shutting down HTTP server", "gracePeriod", s.opts.ShutdownGracePeriod.String())
+		err = s.shutdown(ctx)
+	}
+
+	return errors.Join(err, s.runHooks(ctx))
+}
+
+// shutdown drains the connections, closing the remaining ones when the grace period is over.
+func (s *Server) shutdown(ctx context.Context) error {
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.opts.ShutdownGracePeriod)
+	defer cancel()
+
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		_ = s.server.Close()
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	return nil
+}
+
+// runHooks runs the shutdown hooks in reverse order within the grace period.
+func (s *Server) runHooks(ctx context.Context) error {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.opts.ShutdownGracePeriod)
+	defer cancel()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].hook(hookCtx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s failed: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeStaleSocket removes a socket file left at path by a previous run; other files are kept.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat socket %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("failed to listen on %s: file exists and is not a socket", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+	return nil
+}
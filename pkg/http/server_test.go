@@ -0,0 +1,152 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+}
+
+// startServer runs s in the background and returns the channel receiving the result of Run.
+func startServer(t *testing.T, ctx context.Context, s *Server) <-chan error {
+	t.Helper()
+	require.NoError(t, s.Listen())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	return done
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = client.Do(req) //nolint:bodyclose // reason: closed below
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestNewServer(t *testing.T) {
+	s, err := NewServer(okHandler(), ServerOptions{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultServerReadTimeout, s.server.ReadTimeout)
+	require.Equal(t, DefaultServerWriteTimeout, s.server.WriteTimeout)
+	require.Equal(t, DefaultServerIdleTimeout, s.server.IdleTimeout)
+	require.Equal(t, DefaultServerShutdownGracePeriod, s.opts.ShutdownGracePeriod)
+	require.Equal(t, DefaultHTTPRequestSize, s.server.MaxHeaderBytes)
+	require.Nil(t, s.Addr())
+
+	_, err = NewServer(okHandler(), ServerOptions{TLSCertFile: "cert.pem"})
+	require.ErrorIs(t, err, ErrServerTLSConfig)
+}
+
+func TestServer_Run(t *testing.T) {
+	t.Run("drains requests and runs hooks on cancel", func(t *testing.T) {
+		started := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			_, _ = io.WriteString(w, "slow")
+		})
+
+		s, err := NewServer(handler, ServerOptions{Addr: "127.0.0.1:0"})
+		require.NoError(t, err)
+
+		var order []string
+		s.OnShutdown("db", func(context.Context) error {
+			order = append(order, "db")
+			return nil
+		})
+		s.OnShutdown("flush", func(ctx context.Context) error {
+			order = append(order, "flush")
+			return ctx.Err()
+		})
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := startServer(t, ctx, s)
+
+		body := make(chan string, 1)
+		go func() {
+			body <- get(t, http.DefaultClient, "http://"+s.Addr().String())
+		}()
+		<-started
+		cancel()
+
+		require.Equal(t, "slow", <-body)
+		require.NoError(t, <-done)
+		require.Equal(t, []string{"flush", "db"}, order)
+	})
+
+	t.Run("hook errors are returned", func(t *testing.T) {
+		s, err := NewServer(okHandler(), ServerOptions{Addr: "127.0.0.1:0"})
+		require.NoError(t, err)
+
+		hookErr := errors.New("flush failed")
+		s.OnShutdown("flush", func(context.Context) error { return hookErr })
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		err = s.Run(ctx)
+		require.ErrorIs(t, err, hookErr)
+		require.ErrorContains(t, err, "shutdown hook flush failed")
+	})
+
+	t.Run("listen error runs hooks", func(t *testing.T) {
+		busy, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer func() { _ = busy.Close() }()
+
+		s, err := NewServer(okHandler(), ServerOptions{Addr: busy.Addr().String()})
+		require.NoError(t, err)
+
+		ran := false
+		s.OnShutdown("db", func(context.Context) error {
+			ran = true
+			return nil
+		})
+		require.Error(t, s.Run(t.Context()))
+		require.True(t, ran)
+	})
+}
+
+func TestServer_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(okHandler())
+	defer ts.Close()
+
+	s, err := NewServer(okHandler(), ServerOptions{
+		Addr:      "127.0.0.1:0",
+		TLSConfig: &tls.Config{Certificates: ts.TLS.Certificates, MinVersion: tls.VersionTLS12},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := startServer(t, ctx, s)
+
+	require.Equal(t, "ok", get(t, ts.Client(), "https://"+s.Addr().String()))
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Signal(t *testing.T) {
+	s, err := NewServer(okHandler(), ServerOptions{Addr: "127.0.0.1:0", Signals: []os.Signal{syscall.SIGUSR1}})
+	require.NoError(t, err)
+
+	done := startServer(t, t.Context(), s)
+	require.Equal(t, "ok", get(t, http.DefaultClient, "http://"+s.Addr().String()))
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop on signal")
+	}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "server.sock")
+
+	// A socket left by a previous run is removed.
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	s, err := NewServer(okHandler(), ServerOptions{UnixSocket: socket})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := startServer(t, ctx, s)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+	require.Equal(t, "ok", get(t, client, "http://unix/"))
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoFileExists(t, socket)
+
+	t.Run("regular file is kept", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+		s, err := NewServer(okHandler(), ServerOptions{UnixSocket: path})
+		require.NoError(t, err)
+		require.ErrorContains(t, s.Listen(), "not a socket")
+		require.FileExists(t, path)
+	})
+}
@@ -2,10 +2,12 @@
 package maps
 
 import (
+	"bytes"
 	"fmt"
-	"os"
 	"sort"
 	"sync"
+
+	"github.com/hibare/GoCommon/v2/pkg/file"
 )
 
 // Contains checks if a key is present in map m.
@@ -71,24 +73,18 @@ func SortByValues[K comparable, V any](m map[K]V, less func(a, b V) bool) map[K]
 }
 
 // ToEnvFile writes the map to a file where each line is key=value.
+// The file is replaced atomically, so a crash never leaves it truncated.
 func ToEnvFile[K comparable, V any](m map[K]V, filePath string) error {
-	// Open or create the file for writing
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = file.Close()
-	}()
+	var buf bytes.Buffer
 
-	// Iterate over the map and write each key-value pair to the file
+	// Iterate over the map and write each key-value pair to the buffer
 	for key, value := range m {
-		if _, err := fmt.Fprintf(file, "%v=%v\n", key, value); err != nil {
+		if _, err := fmt.Fprintf(&buf, "%v=%v\n", key, value); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return file.WriteFileAtomic(filePath, buf.Bytes(), file.AtomicWriteOptions{})
 }
 
 // FromSyncMap converts a sync.Map to a regular map.
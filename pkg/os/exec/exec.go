@@ -18,6 +18,7 @@ type ExecIface interface {
 type CmdIface interface {
 	WithEnv(env []string) CmdIface
 	WithDir(dir string) CmdIface
+	WithStdin(stdin *os.File) CmdIface
 	WithStdout(stdout *os.File) CmdIface
 	WithStderr(stderr *os.File) CmdIface
 
@@ -58,6 +59,12 @@ func (c *Cmd) WithDir(dir string) CmdIface {
 	return c
 }
 
+// WithStdin sets the stdin for the command.
+func (c *Cmd) WithStdin(stdin *os.File) CmdIface {
+	c.cmd.Stdin = stdin
+	return c
+}
+
 // WithStdout sets the stdout for the command.
 func (c *Cmd) WithStdout(stdout *os.File) CmdIface {
 	c.cmd.Stdout = stdout
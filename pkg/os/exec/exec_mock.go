@@ -43,6 +43,12 @@ func (_m *MockCmdIface) WithDir(dir string) CmdIface {
 	return mockArgs.Get(0).(CmdIface)
 }
 
+// WithStdin provides a mock function with given fields: stdin
+func (_m *MockCmdIface) WithStdin(stdin *os.File) CmdIface {
+	mockArgs := _m.Called(stdin)
+	return mockArgs.Get(0).(CmdIface)
+}
+
 // WithStdout provides a mock function with given fields: stdout
 func (_m *MockCmdIface) WithStdout(stdout *os.File) CmdIface {
 	mockArgs := _m.Called(stdout)
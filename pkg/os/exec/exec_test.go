@@ -128,6 +128,34 @@ func TestCmd_WithDir(t *testing.T) {
 	}
 }
 
+func TestCmd_WithStdin(t *testing.T) {
+	executor := NewExec()
+	ctx := t.Context()
+
+	// Create a temporary file for stdin
+	stdinFile, err := os.CreateTemp(t.TempDir(), "stdin_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp stdin file: %v", err)
+	}
+	defer func() { _ = stdinFile.Close() }()
+
+	if _, err := stdinFile.WriteString("hello stdin"); err != nil {
+		t.Fatalf("Failed to write stdin file: %v", err)
+	}
+	if _, err := stdinFile.Seek(0, 0); err != nil {
+		t.Fatalf("Failed to seek stdin file: %v", err)
+	}
+
+	output, err := executor.Command(ctx, "cat").WithStdin(stdinFile).Output()
+	if err != nil {
+		t.Fatalf("Failed to run cat command: %v", err)
+	}
+
+	if string(output) != "hello stdin" {
+		t.Errorf("Expected output 'hello stdin', got '%s'", string(output))
+	}
+}
+
 func TestCmd_WithStdout(t *testing.T) {
 	executor := NewExec()
 	ctx := t.Context()